@@ -0,0 +1,193 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+// Plugin states, as used by Nagios and Icinga
+const (
+	CheckOK       = 0
+	CheckWarning  = 1
+	CheckCritical = 2
+	CheckUnknown  = 3
+)
+
+var checkStateNames = map[int]string{
+	CheckOK:       "OK",
+	CheckWarning:  "WARNING",
+	CheckCritical: "CRITICAL",
+	CheckUnknown:  "UNKNOWN",
+}
+
+var checkWarnSealed int
+var checkCritSealed int
+var checkWarnUnreachable int
+var checkCritUnreachable int
+var checkCritNoLeader bool
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the status of all vaults as a Nagios/Icinga plugin",
+	Long: `Checks the status of all vaults in the configuration file and
+prints a single plugin result line with perfdata, exiting with the
+standard plugin exit codes: 0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN.
+Thresholds are the number of hosts at which the state is raised, and
+0 disables a threshold`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// plugins are expected to print a single line
+		if !debug {
+			log.SetOutput(ioutil.Discard)
+		}
+
+		// a broken config or bad flags must still produce a plugin result
+		targets, err := selectTargets()
+		if err != nil {
+			fmt.Printf("HOOKPICK %s - %s\n", checkStateNames[CheckUnknown], err)
+			os.Exit(CheckUnknown)
+		}
+
+		state, line := CheckResults(collectStatusOf(targets).Sorted())
+		fmt.Println(line)
+		os.Exit(state)
+	},
+}
+
+// CheckResults works out the plugin state and output line for a set of results
+func CheckResults(results []HostResult) (int, string) {
+
+	if len(results) == 0 {
+		return CheckUnknown, "HOOKPICK UNKNOWN - no hosts to check"
+	}
+
+	var sealed, unreachable, uninitialized int
+	leaders := make(map[string]int)
+	for _, result := range results {
+		if _, ok := leaders[result.Datacenter]; !ok {
+			leaders[result.Datacenter] = 0
+		}
+
+		switch {
+		case result.Unreachable:
+			unreachable++
+		case !result.Initialized:
+			uninitialized++
+		case result.Sealed:
+			sealed++
+		}
+
+		if result.Role == v.RoleActive {
+			leaders[result.Datacenter]++
+		}
+	}
+
+	var noLeader []string
+	totalLeaders := 0
+	for dcName, count := range leaders {
+		totalLeaders += count
+		if count == 0 {
+			noLeader = append(noLeader, dcName)
+		}
+	}
+	sort.Strings(noLeader)
+
+	state := CheckOK
+	raise := func(to int) {
+		if to > state {
+			state = to
+		}
+	}
+
+	raise(thresholdState(sealed, checkWarnSealed, checkCritSealed))
+	raise(thresholdState(unreachable, checkWarnUnreachable, checkCritUnreachable))
+	if uninitialized > 0 {
+		raise(CheckWarning)
+	}
+	if len(noLeader) > 0 {
+		if checkCritNoLeader {
+			raise(CheckCritical)
+		} else {
+			raise(CheckWarning)
+		}
+	}
+
+	summary := []string{
+		fmt.Sprintf("%d/%d sealed", sealed, len(results)),
+		fmt.Sprintf("%d unreachable", unreachable),
+	}
+	if uninitialized > 0 {
+		summary = append(summary, fmt.Sprintf("%d not initialized", uninitialized))
+	}
+	if len(noLeader) > 0 {
+		summary = append(summary, fmt.Sprintf("no leader in %s", strings.Join(noLeader, ", ")))
+	}
+
+	perfdata := []string{
+		fmt.Sprintf("hosts=%d;;;0", len(results)),
+		fmt.Sprintf("sealed=%d;%s;%s;0;%d", sealed, thresholdString(checkWarnSealed), thresholdString(checkCritSealed), len(results)),
+		fmt.Sprintf("unreachable=%d;%s;%s;0;%d", unreachable, thresholdString(checkWarnUnreachable), thresholdString(checkCritUnreachable), len(results)),
+		fmt.Sprintf("uninitialized=%d;;;0;%d", uninitialized, len(results)),
+		fmt.Sprintf("leaders=%d;;;0", totalLeaders),
+	}
+
+	return state, fmt.Sprintf("HOOKPICK %s - %s | %s",
+		checkStateNames[state], strings.Join(summary, ", "), strings.Join(perfdata, " "))
+}
+
+// thresholdState raises WARNING or CRITICAL when count reaches a threshold
+func thresholdState(count, warn, crit int) int {
+	switch {
+	case crit > 0 && count >= crit:
+		return CheckCritical
+	case warn > 0 && count >= warn:
+		return CheckWarning
+	}
+	return CheckOK
+}
+
+func thresholdString(threshold int) string {
+	if threshold <= 0 {
+		return ""
+	}
+	return fmt.Sprint(threshold)
+}
+
+func init() {
+	RootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().IntVar(&checkWarnSealed, "warn-sealed", 1, "Number of sealed hosts to return WARNING at")
+	checkCmd.Flags().IntVar(&checkCritSealed, "crit-sealed", 0, "Number of sealed hosts to return CRITICAL at")
+	checkCmd.Flags().IntVar(&checkWarnUnreachable, "warn-unreachable", 1, "Number of unreachable hosts to return WARNING at")
+	checkCmd.Flags().IntVar(&checkCritUnreachable, "crit-unreachable", 0, "Number of unreachable hosts to return CRITICAL at")
+	checkCmd.Flags().BoolVar(&checkCritNoLeader, "crit-no-leader", false, "Return CRITICAL rather than WARNING when a datacenter has no active node")
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"testing"
+
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+func TestCheckResults(t *testing.T) {
+
+	defer func(warnSealed, critSealed, warnUnreachable, critUnreachable int, critNoLeader bool) {
+		checkWarnSealed, checkCritSealed = warnSealed, critSealed
+		checkWarnUnreachable, checkCritUnreachable = warnUnreachable, critUnreachable
+		checkCritNoLeader = critNoLeader
+	}(checkWarnSealed, checkCritSealed, checkWarnUnreachable, checkCritUnreachable, checkCritNoLeader)
+
+	active := HostResult{Datacenter: "dc1", Host: "vault-1", Initialized: true, Role: v.RoleActive}
+	standby := HostResult{Datacenter: "dc1", Host: "vault-2", Initialized: true, Role: v.RoleStandby}
+	sealed := HostResult{Datacenter: "dc1", Host: "vault-3", Initialized: true, Sealed: true, Role: v.RoleSealed}
+	unreachable := HostResult{Datacenter: "dc1", Host: "vault-4", Unreachable: true}
+	uninitialized := HostResult{Datacenter: "dc1", Host: "vault-5"}
+	otherDC := HostResult{Datacenter: "dc2", Host: "vault-6", Initialized: true, Role: v.RoleStandby}
+
+	tests := []struct {
+		name            string
+		results         []HostResult
+		warnSealed      int
+		critSealed      int
+		warnUnreachable int
+		critUnreachable int
+		critNoLeader    bool
+		wantState       int
+		wantLine        string
+	}{
+		{
+			name:      "no hosts",
+			wantState: CheckUnknown,
+			wantLine:  "HOOKPICK UNKNOWN - no hosts to check",
+		},
+		{
+			name:            "healthy",
+			results:         []HostResult{active, standby},
+			warnSealed:      1,
+			warnUnreachable: 1,
+			wantState:       CheckOK,
+			wantLine:        "HOOKPICK OK - 0/2 sealed, 0 unreachable | hosts=2;;;0 sealed=0;1;;0;2 unreachable=0;1;;0;2 uninitialized=0;;;0;2 leaders=1;;;0",
+		},
+		{
+			name:       "sealed warning",
+			results:    []HostResult{active, standby, sealed},
+			warnSealed: 1,
+			critSealed: 2,
+			wantState:  CheckWarning,
+			wantLine:   "HOOKPICK WARNING - 1/3 sealed, 0 unreachable | hosts=3;;;0 sealed=1;1;2;0;3 unreachable=0;;;0;3 uninitialized=0;;;0;3 leaders=1;;;0",
+		},
+		{
+			name:       "sealed critical",
+			results:    []HostResult{active, sealed, sealed},
+			warnSealed: 1,
+			critSealed: 2,
+			wantState:  CheckCritical,
+			wantLine:   "HOOKPICK CRITICAL - 2/3 sealed, 0 unreachable | hosts=3;;;0 sealed=2;1;2;0;3 unreachable=0;;;0;3 uninitialized=0;;;0;3 leaders=1;;;0",
+		},
+		{
+			name:      "thresholds disabled",
+			results:   []HostResult{active, sealed, unreachable},
+			wantState: CheckOK,
+			wantLine:  "HOOKPICK OK - 1/3 sealed, 1 unreachable | hosts=3;;;0 sealed=1;;;0;3 unreachable=1;;;0;3 uninitialized=0;;;0;3 leaders=1;;;0",
+		},
+		{
+			name:            "unreachable critical",
+			results:         []HostResult{active, unreachable},
+			warnUnreachable: 1,
+			critUnreachable: 1,
+			wantState:       CheckCritical,
+			wantLine:        "HOOKPICK CRITICAL - 0/2 sealed, 1 unreachable | hosts=2;;;0 sealed=0;;;0;2 unreachable=1;1;1;0;2 uninitialized=0;;;0;2 leaders=1;;;0",
+		},
+		{
+			name:      "uninitialized",
+			results:   []HostResult{active, uninitialized},
+			wantState: CheckWarning,
+			wantLine:  "HOOKPICK WARNING - 0/2 sealed, 0 unreachable, 1 not initialized | hosts=2;;;0 sealed=0;;;0;2 unreachable=0;;;0;2 uninitialized=1;;;0;2 leaders=1;;;0",
+		},
+		{
+			name:      "no leader",
+			results:   []HostResult{active, otherDC},
+			wantState: CheckWarning,
+			wantLine:  "HOOKPICK WARNING - 0/2 sealed, 0 unreachable, no leader in dc2 | hosts=2;;;0 sealed=0;;;0;2 unreachable=0;;;0;2 uninitialized=0;;;0;2 leaders=1;;;0",
+		},
+		{
+			name:         "no leader critical",
+			results:      []HostResult{standby, otherDC},
+			critNoLeader: true,
+			wantState:    CheckCritical,
+			wantLine:     "HOOKPICK CRITICAL - 0/2 sealed, 0 unreachable, no leader in dc1, dc2 | hosts=2;;;0 sealed=0;;;0;2 unreachable=0;;;0;2 uninitialized=0;;;0;2 leaders=0;;;0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkWarnSealed, checkCritSealed = test.warnSealed, test.critSealed
+			checkWarnUnreachable, checkCritUnreachable = test.warnUnreachable, test.critUnreachable
+			checkCritNoLeader = test.critNoLeader
+
+			state, line := CheckResults(test.results)
+			if state != test.wantState {
+				t.Errorf("CheckResults() state = %d, want %d", state, test.wantState)
+			}
+			if line != test.wantLine {
+				t.Errorf("CheckResults() line =\n%s\nwant\n%s", line, test.wantLine)
+			}
+		})
+	}
+}
+
+func TestThresholdState(t *testing.T) {
+
+	tests := []struct {
+		count, warn, crit int
+		want              int
+	}{
+		{count: 0, warn: 1, crit: 2, want: CheckOK},
+		{count: 1, warn: 1, crit: 2, want: CheckWarning},
+		{count: 2, warn: 1, crit: 2, want: CheckCritical},
+		{count: 5, warn: 1, crit: 2, want: CheckCritical},
+		{count: 5, warn: 0, crit: 0, want: CheckOK},
+		{count: 1, warn: 0, crit: 1, want: CheckCritical},
+		{count: 3, warn: 3, crit: 0, want: CheckWarning},
+	}
+
+	for _, test := range tests {
+		if got := thresholdState(test.count, test.warn, test.crit); got != test.want {
+			t.Errorf("thresholdState(%d, %d, %d) = %d, want %d", test.count, test.warn, test.crit, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/jaxxstorm/hookpick/config"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the configuration file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration file for mistakes",
+	Long: `Checks the configuration file for duplicate datacenters and
+hosts, hosts without ports, invalid ports, datacenters without keys,
+keys that aren't base64 when gpg is enabled and unknown fields, and
+prints each problem with the line it is on. This runs automatically
+before every command that changes vault`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		problems, err := checkConfig()
+		if err != nil {
+			log.Fatal("Error validating config file: ", err)
+		}
+
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+
+		log.WithFields(log.Fields{
+			"config": viper.ConfigFileUsed(),
+		}).Infoln("Config file is valid")
+	},
+}
+
+// checkConfig validates the config file in use
+func checkConfig() ([]config.Problem, error) {
+
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return nil, fmt.Errorf("no config file found")
+	}
+
+	return config.Validate(path)
+}
+
+// validateConfig exits if the config file in use has any problems
+func validateConfig() {
+
+	problems, err := checkConfig()
+	if err == config.ErrUnsupportedFormat {
+		log.WithFields(log.Fields{
+			"config": viper.ConfigFileUsed(),
+		}).Warnln("Config file format can't be validated, skipping validation")
+		return
+	}
+	if err != nil {
+		log.Fatal("Error validating config file: ", err)
+	}
+
+	for _, problem := range problems {
+		log.WithFields(log.Fields{
+			"config": problem.File,
+			"line":   problem.Line,
+		}).Errorln(problem.Message)
+	}
+
+	if len(problems) > 0 {
+		log.Fatal("Config file is invalid, refusing to continue: See hookpick config validate")
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
@@ -0,0 +1,147 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+// checkConsistency compares the hosts in each datacenter with each other and
+// records any disagreement about version, cluster ID, leadership or threshold
+// against the hosts involved
+func checkConsistency(results *ResultSet) {
+
+	results.Lock()
+	defer results.Unlock()
+
+	byDC := make(map[string][]*HostResult)
+	var dcNames []string
+	for i := range results.Results {
+		result := &results.Results[i]
+		if _, ok := byDC[result.Datacenter]; !ok {
+			dcNames = append(dcNames, result.Datacenter)
+		}
+		byDC[result.Datacenter] = append(byDC[result.Datacenter], result)
+	}
+	sort.Strings(dcNames)
+
+	for _, dcName := range dcNames {
+		hosts := byDC[dcName]
+
+		flagOutliers(hosts, "version", func(r *HostResult) string { return r.Version })
+		flagOutliers(hosts, "cluster ID", func(r *HostResult) string { return r.ClusterID })
+		flagOutliers(hosts, "threshold", func(r *HostResult) string {
+			if r.Threshold == 0 {
+				return ""
+			}
+			return strconv.Itoa(r.Threshold)
+		})
+
+		var leaders []*HostResult
+		for _, host := range hosts {
+			if host.Role == v.RoleActive {
+				leaders = append(leaders, host)
+			}
+		}
+		if len(leaders) > 1 {
+			for _, leader := range leaders {
+				leader.addInconsistency(fmt.Sprintf("one of %d active nodes in the datacenter", len(leaders)))
+			}
+		}
+
+		for _, host := range hosts {
+			for _, inconsistency := range host.Inconsistencies {
+				log.WithFields(log.Fields{
+					"datacenter":    dcName,
+					"host":          host.Host,
+					"port":          host.Port,
+					"inconsistency": inconsistency,
+				}).Errorln("Host is inconsistent with its datacenter")
+			}
+		}
+
+		log.WithFields(log.Fields{
+			"datacenter": dcName,
+			"hosts":      len(hosts),
+			"leaders":    len(leaders),
+		}).Debugln("Checked datacenter consistency")
+	}
+}
+
+// flagOutliers records an inconsistency against every host whose value differs
+// from the most common value in the datacenter. When no single value is the
+// most common, every host that reported a value is flagged. Hosts that didn't
+// report a value are ignored.
+func flagOutliers(hosts []*HostResult, name string, value func(*HostResult) string) {
+
+	counts := make(map[string]int)
+	for _, host := range hosts {
+		if value(host) != "" {
+			counts[value(host)]++
+		}
+	}
+
+	if len(counts) < 2 {
+		return
+	}
+
+	var values []string
+	for val := range counts {
+		values = append(values, val)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+
+	var seen []string
+	for _, val := range values {
+		seen = append(seen, fmt.Sprintf("%s (%d)", val, counts[val]))
+	}
+
+	majority := values[0]
+	tied := counts[values[0]] == counts[values[1]]
+
+	for _, host := range hosts {
+		val := value(host)
+		if val == "" || (!tied && val == majority) {
+			continue
+		}
+		if tied {
+			host.addInconsistency(fmt.Sprintf("%s %s, datacenter has %s", name, val, strings.Join(seen, ", ")))
+		} else {
+			host.addInconsistency(fmt.Sprintf("%s %s, rest of datacenter has %s", name, val, majority))
+		}
+	}
+}
+
+func (r *HostResult) addInconsistency(inconsistency string) {
+	r.Inconsistencies = append(r.Inconsistencies, inconsistency)
+}
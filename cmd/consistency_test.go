@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlagOutliers(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		versions []string
+		want     [][]string
+	}{
+		{
+			name:     "all agree",
+			versions: []string{"1.4.0", "1.4.0", "1.4.0"},
+			want:     [][]string{nil, nil, nil},
+		},
+		{
+			name:     "one outlier",
+			versions: []string{"1.4.0", "1.3.0", "1.4.0"},
+			want:     [][]string{nil, {"version 1.3.0, rest of datacenter has 1.4.0"}, nil},
+		},
+		{
+			name:     "missing values ignored",
+			versions: []string{"1.4.0", "", "1.3.0", "1.4.0"},
+			want:     [][]string{nil, nil, {"version 1.3.0, rest of datacenter has 1.4.0"}, nil},
+		},
+		{
+			name:     "only one reported",
+			versions: []string{"", "1.4.0", ""},
+			want:     [][]string{nil, nil, nil},
+		},
+		{
+			name:     "tie",
+			versions: []string{"1.4.0", "1.3.0"},
+			want: [][]string{
+				{"version 1.4.0, datacenter has 1.3.0 (1), 1.4.0 (1)"},
+				{"version 1.3.0, datacenter has 1.3.0 (1), 1.4.0 (1)"},
+			},
+		},
+		{
+			name:     "tie for most common",
+			versions: []string{"1.4.0", "1.3.0", "1.4.0", "1.3.0", "1.2.0"},
+			want: [][]string{
+				{"version 1.4.0, datacenter has 1.3.0 (2), 1.4.0 (2), 1.2.0 (1)"},
+				{"version 1.3.0, datacenter has 1.3.0 (2), 1.4.0 (2), 1.2.0 (1)"},
+				{"version 1.4.0, datacenter has 1.3.0 (2), 1.4.0 (2), 1.2.0 (1)"},
+				{"version 1.3.0, datacenter has 1.3.0 (2), 1.4.0 (2), 1.2.0 (1)"},
+				{"version 1.2.0, datacenter has 1.3.0 (2), 1.4.0 (2), 1.2.0 (1)"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var hosts []*HostResult
+			for _, version := range test.versions {
+				hosts = append(hosts, &HostResult{Version: version})
+			}
+
+			flagOutliers(hosts, "version", func(r *HostResult) string { return r.Version })
+
+			for i, host := range hosts {
+				if !reflect.DeepEqual(host.Inconsistencies, test.want[i]) {
+					t.Errorf("host %d inconsistencies = %q, want %q", i, host.Inconsistencies, test.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+)
+
+// Exit codes reflecting the state of the fleet after a command has run.
+// When several apply, the highest one wins.
+const (
+	// ExitHealthy : every host is reachable, unsealed and the operation succeeded
+	ExitHealthy = 0
+	// ExitSealed : at least one host is sealed
+	ExitSealed = 2
+	// ExitUnreachable : at least one host could not be reached
+	ExitUnreachable = 3
+	// ExitOperationFailed : the operation failed on at least one host
+	ExitOperationFailed = 4
+	// ExitInconsistent : at least one host disagrees with the rest of its datacenter
+	ExitInconsistent = 5
+)
+
+// ExitCode works out the exit code for a set of results. Sealed hosts are
+// only counted when checkSealed is set, as some commands expect them.
+func ExitCode(results []HostResult, checkSealed bool) int {
+
+	code := ExitHealthy
+
+	for _, result := range results {
+		hostCode := ExitHealthy
+
+		switch {
+		case result.Unreachable:
+			hostCode = ExitUnreachable
+		case result.Error != "":
+			hostCode = ExitOperationFailed
+		case len(result.Inconsistencies) > 0:
+			hostCode = ExitInconsistent
+		case checkSealed && result.Sealed:
+			hostCode = ExitSealed
+		}
+
+		if hostCode > code {
+			code = hostCode
+		}
+	}
+
+	return code
+}
+
+// exitWithResults exits with the code for the results, if it is not healthy
+func exitWithResults(results *ResultSet, checkSealed bool) {
+
+	if code := ExitCode(results.Sorted(), checkSealed); code != ExitHealthy {
+		os.Exit(code)
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import "testing"
+
+func TestExitCode(t *testing.T) {
+
+	healthy := HostResult{Host: "healthy", Initialized: true}
+	sealed := HostResult{Host: "sealed", Initialized: true, Sealed: true}
+	unreachable := HostResult{Host: "unreachable", Unreachable: true, Error: "connection refused"}
+	failed := HostResult{Host: "failed", Initialized: true, Sealed: true, Error: "1 of 3 keys rejected"}
+	inconsistent := HostResult{Host: "inconsistent", Initialized: true, Inconsistencies: []string{"version 1.3.0, others have 1.4.0"}}
+
+	tests := []struct {
+		name        string
+		results     []HostResult
+		checkSealed bool
+		want        int
+	}{
+		{name: "no results", want: ExitHealthy},
+		{name: "healthy", results: []HostResult{healthy, healthy}, checkSealed: true, want: ExitHealthy},
+		{name: "sealed", results: []HostResult{healthy, sealed}, checkSealed: true, want: ExitSealed},
+		{name: "sealed not checked", results: []HostResult{healthy, sealed}, want: ExitHealthy},
+		{name: "unreachable", results: []HostResult{healthy, unreachable}, want: ExitUnreachable},
+		{name: "failed", results: []HostResult{healthy, failed}, want: ExitOperationFailed},
+		{name: "failed while sealed", results: []HostResult{failed}, checkSealed: true, want: ExitOperationFailed},
+		{name: "inconsistent", results: []HostResult{healthy, inconsistent}, want: ExitInconsistent},
+		{name: "highest wins", results: []HostResult{sealed, unreachable, failed}, checkSealed: true, want: ExitOperationFailed},
+		{name: "sealed and unreachable", results: []HostResult{sealed, unreachable}, checkSealed: true, want: ExitUnreachable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ExitCode(test.results, test.checkSealed); got != test.want {
+				t.Errorf("ExitCode() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
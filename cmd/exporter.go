@@ -0,0 +1,239 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+var exporterListen string
+var exporterInterval time.Duration
+
+// exporterCmd represents the exporter command
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "Serve the status of all vaults as Prometheus metrics",
+	Long: `Polls every vault in the configuration file in the background
+and serves the latest results as Prometheus metrics on /metrics`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if exporterInterval <= 0 {
+			log.Fatal("--interval must be greater than 0")
+		}
+
+		// every host is logged on every poll otherwise
+		quietHostStatus = true
+
+		exporter := &Exporter{errors: make(map[string]uint64)}
+		go exporter.Poll(exporterInterval)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter)
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `<html><body><a href="/metrics">Metrics</a></body></html>`)
+		})
+
+		log.WithFields(log.Fields{
+			"listen":   exporterListen,
+			"interval": exporterInterval,
+		}).Infoln("Starting exporter")
+
+		log.Fatal(http.ListenAndServe(exporterListen, mux))
+	},
+}
+
+// Exporter holds the results of the latest poll for serving as metrics
+type Exporter struct {
+	sync.RWMutex
+	results  []HostResult
+	duration time.Duration
+	polled   time.Time
+	polls    uint64
+	// errors counts failed polls per host, keyed by the host's labels
+	errors map[string]uint64
+	// configErr is why the config file couldn't be used on the last poll
+	configErr    error
+	configErrors uint64
+}
+
+// Poll fetches the status of every host every interval, forever
+func (e *Exporter) Poll(interval time.Duration) {
+
+	for {
+		start := time.Now()
+
+		// a broken config must not stop the exporter, it is exported instead
+		targets, err := selectTargets()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Errorln("Error reading config, keeping the previous results")
+			e.configFailed(err)
+		} else {
+			results := collectStatusOf(targets).Sorted()
+			e.update(results, start, time.Since(start))
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// update records the results of a poll
+func (e *Exporter) update(results []HostResult, start time.Time, duration time.Duration) {
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.results = results
+	e.duration = duration
+	e.polled = start
+	e.polls++
+	e.configErr = nil
+	for _, result := range results {
+		if result.Unreachable || result.Error != "" {
+			e.errors[hostLabels(result)]++
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"hosts":    len(results),
+		"duration": duration,
+	}).Debugln("Poll complete")
+}
+
+// configFailed records a poll that couldn't read the config
+func (e *Exporter) configFailed(err error) {
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.configErr = err
+	e.configErrors++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	var buf bytes.Buffer
+	e.WriteMetrics(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// WriteMetrics writes the metrics for the latest poll to w
+func (e *Exporter) WriteMetrics(w io.Writer) {
+
+	e.RLock()
+	defer e.RUnlock()
+
+	hostGauge := func(name, help string, value func(HostResult) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, result := range e.results {
+			fmt.Fprintf(w, "%s{%s} %g\n", name, hostLabels(result), value(result))
+		}
+	}
+
+	hostGauge("hookpick_vault_up", "Whether the vault could be reached.", func(r HostResult) float64 {
+		return boolMetric(!r.Unreachable)
+	})
+	hostGauge("hookpick_vault_initialized", "Whether the vault is initialized.", func(r HostResult) float64 {
+		return boolMetric(r.Initialized)
+	})
+	hostGauge("hookpick_vault_sealed", "Whether the vault is sealed.", func(r HostResult) float64 {
+		return boolMetric(r.Sealed)
+	})
+	hostGauge("hookpick_vault_unseal_progress", "Number of unseal keys submitted towards the threshold.", func(r HostResult) float64 {
+		return float64(r.Progress)
+	})
+	hostGauge("hookpick_vault_unseal_threshold", "Number of unseal keys needed to unseal the vault.", func(r HostResult) float64 {
+		return float64(r.Threshold)
+	})
+	hostGauge("hookpick_vault_is_leader", "Whether the vault is the active node.", func(r HostResult) float64 {
+		return boolMetric(r.Role == v.RoleActive)
+	})
+
+	fmt.Fprintln(w, "# HELP hookpick_scrape_errors_total Number of polls of a vault that failed.")
+	fmt.Fprintln(w, "# TYPE hookpick_scrape_errors_total counter")
+	for _, result := range e.results {
+		labels := hostLabels(result)
+		fmt.Fprintf(w, "hookpick_scrape_errors_total{%s} %d\n", labels, e.errors[labels])
+	}
+
+	fmt.Fprintln(w, "# HELP hookpick_config_valid Whether the config file could be used on the last poll.")
+	fmt.Fprintln(w, "# TYPE hookpick_config_valid gauge")
+	fmt.Fprintf(w, "hookpick_config_valid %g\n", boolMetric(e.configErr == nil))
+
+	fmt.Fprintln(w, "# HELP hookpick_config_errors_total Number of polls that couldn't use the config file.")
+	fmt.Fprintln(w, "# TYPE hookpick_config_errors_total counter")
+	fmt.Fprintf(w, "hookpick_config_errors_total %d\n", e.configErrors)
+
+	fmt.Fprintln(w, "# HELP hookpick_scrape_duration_seconds How long the last poll of every vault took.")
+	fmt.Fprintln(w, "# TYPE hookpick_scrape_duration_seconds gauge")
+	fmt.Fprintf(w, "hookpick_scrape_duration_seconds %g\n", e.duration.Seconds())
+
+	fmt.Fprintln(w, "# HELP hookpick_scrapes_total Number of polls of every vault.")
+	fmt.Fprintln(w, "# TYPE hookpick_scrapes_total counter")
+	fmt.Fprintf(w, "hookpick_scrapes_total %d\n", e.polls)
+
+	if !e.polled.IsZero() {
+		fmt.Fprintln(w, "# HELP hookpick_last_scrape_timestamp_seconds When the last poll of every vault started.")
+		fmt.Fprintln(w, "# TYPE hookpick_last_scrape_timestamp_seconds gauge")
+		fmt.Fprintf(w, "hookpick_last_scrape_timestamp_seconds %d\n", e.polled.Unix())
+	}
+}
+
+// hostLabels returns the Prometheus labels for a host
+func hostLabels(result HostResult) string {
+	return fmt.Sprintf(`datacenter="%s",host="%s",port="%s"`,
+		escapeLabel(result.Datacenter), escapeLabel(result.Host), escapeLabel(result.Port))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func boolMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	RootCmd.AddCommand(exporterCmd)
+
+	exporterCmd.Flags().StringVar(&exporterListen, "listen", ":9750", "Address to serve metrics on")
+	exporterCmd.Flags().DurationVar(&exporterInterval, "interval", 30*time.Second, "How often to poll every vault")
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+func TestEscapeLabel(t *testing.T) {
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"vault-1", "vault-1"},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\vault`, `C:\\vault`},
+		{"two\nlines", `two\nlines`},
+		{`\"`, `\\\"`},
+	}
+
+	for _, test := range tests {
+		if got := escapeLabel(test.value); got != test.want {
+			t.Errorf("escapeLabel(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestHostLabels(t *testing.T) {
+
+	got := hostLabels(HostResult{Datacenter: `dc"1`, Host: "vault-1", Port: "8200"})
+	want := `datacenter="dc\"1",host="vault-1",port="8200"`
+	if got != want {
+		t.Errorf("hostLabels() = %q, want %q", got, want)
+	}
+}
+
+func TestExporterWriteMetrics(t *testing.T) {
+
+	start := time.Unix(1600000000, 0)
+	results := []HostResult{
+		{Datacenter: "dc1", Host: "vault-1", Port: "8200", Initialized: true, Threshold: 3, Role: v.RoleActive},
+		{Datacenter: "dc1", Host: "vault-2", Port: "8200", Initialized: true, Sealed: true, Progress: 1, Threshold: 3},
+		{Datacenter: "dc1", Host: "vault-3", Port: "8200", Unreachable: true, Error: "connection refused"},
+	}
+
+	exporter := &Exporter{errors: make(map[string]uint64)}
+	exporter.update(results, start, 1500*time.Millisecond)
+	exporter.update(results, start, 1500*time.Millisecond)
+	exporter.configFailed(errors.New("no datacenter in the config file matches \"nope\""))
+
+	var buf bytes.Buffer
+	exporter.WriteMetrics(&buf)
+	metrics := buf.String()
+
+	want := []string{
+		"# TYPE hookpick_vault_up gauge",
+		`hookpick_vault_up{datacenter="dc1",host="vault-1",port="8200"} 1`,
+		`hookpick_vault_up{datacenter="dc1",host="vault-3",port="8200"} 0`,
+		`hookpick_vault_initialized{datacenter="dc1",host="vault-3",port="8200"} 0`,
+		`hookpick_vault_sealed{datacenter="dc1",host="vault-2",port="8200"} 1`,
+		`hookpick_vault_unseal_progress{datacenter="dc1",host="vault-2",port="8200"} 1`,
+		`hookpick_vault_unseal_threshold{datacenter="dc1",host="vault-2",port="8200"} 3`,
+		`hookpick_vault_is_leader{datacenter="dc1",host="vault-1",port="8200"} 1`,
+		`hookpick_vault_is_leader{datacenter="dc1",host="vault-2",port="8200"} 0`,
+		"# TYPE hookpick_scrape_errors_total counter",
+		`hookpick_scrape_errors_total{datacenter="dc1",host="vault-1",port="8200"} 0`,
+		`hookpick_scrape_errors_total{datacenter="dc1",host="vault-3",port="8200"} 2`,
+		"hookpick_config_valid 0",
+		"hookpick_config_errors_total 1",
+		"hookpick_scrape_duration_seconds 1.5",
+		"hookpick_scrapes_total 2",
+		"hookpick_last_scrape_timestamp_seconds 1600000000",
+	}
+
+	lines := strings.Split(metrics, "\n")
+	for _, line := range want {
+		if !containsLine(lines, line) {
+			t.Errorf("WriteMetrics() is missing %q in\n%s", line, metrics)
+		}
+	}
+
+	// a successful poll clears the config error
+	exporter.update(results, start, time.Second)
+	buf.Reset()
+	exporter.WriteMetrics(&buf)
+	if !containsLine(strings.Split(buf.String(), "\n"), "hookpick_config_valid 1") {
+		t.Errorf("WriteMetrics() after a good poll = \n%s\nwant hookpick_config_valid 1", buf.String())
+	}
+}
+
+func TestExporterWriteMetricsBeforePoll(t *testing.T) {
+
+	exporter := &Exporter{errors: make(map[string]uint64)}
+
+	var buf bytes.Buffer
+	exporter.WriteMetrics(&buf)
+	metrics := buf.String()
+
+	if strings.Contains(metrics, "hookpick_last_scrape_timestamp_seconds") {
+		t.Errorf("WriteMetrics() before a poll has a last scrape timestamp:\n%s", metrics)
+	}
+	if !containsLine(strings.Split(metrics, "\n"), "hookpick_scrapes_total 0") {
+		t.Errorf("WriteMetrics() before a poll = \n%s\nwant hookpick_scrapes_total 0", metrics)
+	}
+}
+
+func containsLine(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
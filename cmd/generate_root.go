@@ -0,0 +1,627 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/jaxxstorm/hookpick/config"
+	"github.com/jaxxstorm/hookpick/gpg"
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+var generateRootOTP string
+var generateRootPGPKey string
+
+// generateRootCmd represents the generate-root command
+var generateRootCmd = &cobra.Command{
+	Use:   "generate-root",
+	Short: "Runs generate root operations against Vault servers",
+	Long: `Generates a new root token for all Vault servers
+or specified Vault servers in the configuration file, using
+the unseal keys configured for each datacenter`,
+}
+
+var generateRootInitCmd = &cobra.Command{
+	Use:         "init",
+	Annotations: mutating,
+	Short:       "Starts a root token generation on specified Vault servers",
+	Long: `Initialises a root token generation against specified Vault servers
+and returns the nonce needed for other operators. If neither an OTP
+nor a PGP key is given, an OTP is generated and printed`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if generateRootOTP != "" && generateRootPGPKey != "" {
+			log.Fatal("Please specify either an OTP or a PGP key, not both: See --help")
+		}
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			go ProcessGenerateRoot(&wg, dc, configHelper, v.NewVaultHelper, HostGenerateRootInit, results)
+		}
+		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, true)
+	},
+}
+
+var generateRootSubmitCmd = &cobra.Command{
+	Use:         "submit",
+	Annotations: mutating,
+	Short:       "Submits your key to the root token generation",
+	Long: `Submits your unseal key to the root token generation
+and decodes the root token once the threshold is reached`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+		gpgHelper := gpg.NewGPGHelper(gpg.Decrypt)
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			go ProcessGenerateRootSubmit(&wg, dc, configHelper, v.NewVaultHelper, gpgHelper, GetVaultKeys, HostGenerateRootSubmit, results)
+		}
+		wg.Wait()
+
+		printRootTokens(os.Stdout, results.Sorted())
+		renderResults(results)
+		exitWithResults(results, true)
+	},
+}
+
+var generateRootStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Retrieves the current status of a root token generation",
+	Long: `Retrieves the current status of a root token generation
+from all the specified Vault servers`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			go ProcessGenerateRoot(&wg, dc, configHelper, v.NewVaultHelper, HostGenerateRootStatus, results)
+		}
+		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, true)
+	},
+}
+
+var generateRootCancelCmd = &cobra.Command{
+	Use:         "cancel",
+	Annotations: mutating,
+	Short:       "Cancels a root token generation",
+	Long: `Cancels any in progress root token generation
+on all the specified Vault servers`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			go ProcessGenerateRoot(&wg, dc, configHelper, v.NewVaultHelper, HostGenerateRootCancel, results)
+		}
+		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, true)
+	},
+}
+
+func ProcessGenerateRoot(wg *sync.WaitGroup,
+	dc config.Datacenter,
+	configHelper *ConfigHelper,
+	vhGetter v.VaultHelperGetter,
+	hostGenerateRoot HostImpl,
+	results *ResultSet) {
+	defer wg.Done()
+
+	caPath := configHelper.GetCAPath()
+
+	log.WithFields(log.Fields{
+		"datacenter": dc.Name,
+	}).Debugln("Processing generate root for")
+
+	hwg := sync.WaitGroup{}
+	for _, host := range dc.Hosts {
+		hwg.Add(1)
+		log.WithFields(log.Fields{
+			"host": host.Name,
+		}).Debugln("Starting to process generate root")
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		go hostGenerateRoot(&hwg, vaultHelper, results)
+	}
+	hwg.Wait()
+}
+
+func ProcessGenerateRootSubmit(wg *sync.WaitGroup,
+	dc config.Datacenter,
+	configHelper *ConfigHelper,
+	vhGetter v.VaultHelperGetter,
+	gpgHelper *gpg.GPGHelper,
+	vaultKeysGetter VaultKeyGetter,
+	submitHostGenerateRoot HostSubmitImpl,
+	results *ResultSet) {
+	defer wg.Done()
+
+	caPath := configHelper.GetCAPath()
+
+	log.WithFields(log.Fields{
+		"datacenter": dc.Name,
+	}).Debugln("Processing generate root for")
+
+	vaultKeys := vaultKeysGetter(dc, configHelper.GetGPGKey, gpgHelper.Decrypt)
+
+	hwg := sync.WaitGroup{}
+	for _, host := range dc.Hosts {
+		hwg.Add(1)
+		log.WithFields(log.Fields{
+			"host": host.Name,
+		}).Debugln("Starting to process generate root")
+
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		go submitHostGenerateRoot(&hwg, vaultHelper, vaultKeys, results)
+	}
+	hwg.Wait()
+}
+
+func HostGenerateRootInit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
+	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
+	client, err := vaultHelper.GetVaultClient()
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host": vaultHelper.HostName,
+			"port": vaultHelper.Port,
+		}).Errorln(err)
+		hostResult.SetError(err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting generate root init")
+
+	// check init status
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
+
+	if init == true && sealed == false {
+		// get the current leader to operate on
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return
+		}
+		// if we are the leader start the generation
+		if result.IsSelf == true {
+			status, err := client.Sys().GenerateRootStatus()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"host":  vaultHelper.HostName,
+					"error": err,
+				}).Errorln("Error getting generate root status")
+				hostResult.SetError(err)
+				return
+			}
+
+			if status.Started {
+				log.WithFields(log.Fields{
+					"host":  vaultHelper.HostName,
+					"nonce": status.Nonce,
+				}).Errorln("Root token generation already in progress")
+				hostResult.SetError(errors.New("root token generation already in progress"))
+				return
+			}
+
+			otp := generateRootOTP
+			pgpKey := ""
+
+			if generateRootPGPKey != "" {
+				pgpKey, err = gpg.ReadPublicKeyFile(generateRootPGPKey)
+				if err != nil {
+					log.Errorln("Error reading PGP key ", err)
+					hostResult.SetError(err)
+					return
+				}
+			} else if otp == "" {
+				otp, err = v.GenerateOTP(status.OTPLength)
+				if err != nil {
+					log.Errorln("Error generating OTP ", err)
+					hostResult.SetError(err)
+					return
+				}
+			}
+
+			initResult, err := client.Sys().GenerateRootInit(otp, pgpKey)
+			if err != nil {
+				log.Errorln("Generate root init error ", err)
+				hostResult.SetError(err)
+				return
+			}
+
+			// newer versions of vault generate the OTP themselves
+			if initResult.OTP != "" {
+				otp = initResult.OTP
+			}
+
+			fields := log.Fields{
+				"host":     vaultHelper.HostName,
+				"nonce":    initResult.Nonce,
+				"required": initResult.Required,
+			}
+			if initResult.PGPFingerprint != "" {
+				fields["pgp_fingerprint"] = initResult.PGPFingerprint
+			} else {
+				fields["otp"] = otp
+			}
+
+			log.WithFields(fields).Infoln("Root token generation started. Please supply your keys.")
+			hostResult.Message = "Root token generation started"
+			hostResult.Threshold = initResult.Required
+		}
+	}
+}
+
+func HostGenerateRootStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
+	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
+	client, err := vaultHelper.GetVaultClient()
+
+	if err != nil {
+		log.WithFields(log.Fields{"host": vaultHelper.HostName, "port": vaultHelper.Port}).Error(err)
+		hostResult.SetError(err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting generate root status")
+
+	// check init status
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
+
+	if init == true && sealed == false {
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return
+		}
+		if result.IsSelf == true {
+			status, err := client.Sys().GenerateRootStatus()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"host":  vaultHelper.HostName,
+					"port":  vaultHelper.Port,
+					"error": err,
+				}).Errorln("Error getting generate root status")
+				hostResult.SetError(err)
+				return
+			}
+			if status.Started {
+				log.WithFields(log.Fields{
+					"host":            vaultHelper.HostName,
+					"nonce":           status.Nonce,
+					"progress":        status.Progress,
+					"required":        status.Required,
+					"pgp_fingerprint": status.PGPFingerprint,
+				}).Infoln("Root token generation has been started")
+				hostResult.Message = "Root token generation has been started"
+				hostResult.Progress = status.Progress
+				hostResult.Threshold = status.Required
+			} else {
+				log.WithFields(log.Fields{
+					"host": vaultHelper.HostName,
+				}).Infoln("Root token generation not started")
+				hostResult.Message = "Root token generation not started"
+			}
+		}
+	}
+}
+
+func HostGenerateRootCancel(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
+	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
+	client, err := vaultHelper.GetVaultClient()
+
+	if err != nil {
+		log.WithFields(log.Fields{"host": vaultHelper.HostName, "port": vaultHelper.Port}).Error(err)
+		hostResult.SetError(err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting generate root cancel")
+
+	// check init status
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
+
+	if init == true && sealed == false {
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return
+		}
+		if result.IsSelf == true {
+			if err := client.Sys().GenerateRootCancel(); err != nil {
+				log.WithFields(log.Fields{
+					"host":  vaultHelper.HostName,
+					"port":  vaultHelper.Port,
+					"error": err,
+				}).Errorln("Error cancelling root token generation")
+				hostResult.SetError(err)
+				return
+			}
+			log.WithFields(log.Fields{
+				"host": vaultHelper.HostName,
+			}).Infoln("Root token generation cancelled")
+			hostResult.Message = "Root token generation cancelled"
+		}
+	}
+}
+
+func HostGenerateRootSubmit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys []string, results *ResultSet) bool {
+	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
+	client, err := vaultHelper.GetVaultClient()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"port":  vaultHelper.Port,
+			"error": err,
+		}).Errorln("Error getting vault client")
+		hostResult.SetError(err)
+		return false
+	}
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting generate root submit")
+
+	// check init status
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return false
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
+
+	if init == true && sealed == false {
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return false
+		}
+		if result.IsSelf == true {
+			status, err := client.Sys().GenerateRootStatus()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"host":  vaultHelper.HostName,
+					"port":  vaultHelper.Port,
+					"error": err,
+				}).Errorln("Error getting generate root status")
+				hostResult.SetError(err)
+				return false
+			}
+
+			if !status.Started {
+				log.WithFields(log.Fields{
+					"host": vaultHelper.HostName,
+				}).Infoln("Root token generation not started")
+				hostResult.Message = "Root token generation not started"
+				return true
+			}
+
+			for _, vaultKey := range vaultKeys {
+				update, err := client.Sys().GenerateRootUpdate(vaultKey, status.Nonce)
+				if err != nil {
+					log.WithFields(log.Fields{
+						"host":  vaultHelper.HostName,
+						"port":  vaultHelper.Port,
+						"error": err,
+					}).Errorln("Error updating root token generation")
+					hostResult.SetError(err)
+					continue
+				}
+
+				if !update.Complete {
+					log.WithFields(log.Fields{
+						"host":     vaultHelper.HostName,
+						"nonce":    update.Nonce,
+						"progress": update.Progress,
+						"required": update.Required,
+					}).Infoln("Key submitted")
+					hostResult.Message = "Key submitted"
+					hostResult.Progress = update.Progress
+					hostResult.Threshold = update.Required
+					continue
+				}
+
+				encoded := update.EncodedRootToken
+				if encoded == "" {
+					encoded = update.EncodedToken
+				}
+
+				hostLogger := log.WithFields(log.Fields{
+					"host": vaultHelper.HostName,
+				})
+
+				switch {
+				case update.PGPFingerprint != "":
+					hostLogger.WithFields(log.Fields{
+						"pgp_fingerprint": update.PGPFingerprint,
+						"encoded_token":   encoded,
+					}).Infoln("Root token generated. Decrypt it with the matching PGP key.")
+					hostResult.Message = "Root token generated"
+				case generateRootOTP != "":
+					rootToken, err := v.DecodeRootToken(encoded, generateRootOTP)
+					if err != nil {
+						hostLogger.WithFields(log.Fields{
+							"encoded_token": encoded,
+							"error":         err,
+						}).Errorln("Error decoding root token")
+						hostResult.SetError(err)
+						return false
+					}
+					// the token is only ever printed to stdout, never logged
+					hostLogger.Infoln("Root token generated")
+					hostResult.Message = "Root token generated"
+					hostResult.RootToken = rootToken
+				default:
+					hostLogger.WithFields(log.Fields{
+						"encoded_token": encoded,
+					}).Infoln("Root token generated. Pass --otp to decode it.")
+					hostResult.Message = "Root token generated"
+				}
+
+				break
+			}
+		}
+	}
+	return true
+}
+
+// printRootTokens prints each decoded root token to stdout, unless --output
+// was given and they are included in the results instead
+func printRootTokens(w io.Writer, results []HostResult) {
+
+	if outputFormat != "" {
+		return
+	}
+
+	for _, result := range results {
+		if result.RootToken != "" {
+			fmt.Fprintf(w, "Root token for %s (%s): %s\n", result.Datacenter, result.Host, result.RootToken)
+		}
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(generateRootCmd)
+	generateRootCmd.AddCommand(generateRootInitCmd)
+	generateRootCmd.AddCommand(generateRootSubmitCmd)
+	generateRootCmd.AddCommand(generateRootStatusCmd)
+	generateRootCmd.AddCommand(generateRootCancelCmd)
+
+	generateRootInitCmd.Flags().StringVar(&generateRootOTP, "otp", "", "The OTP to encode the root token with (generated if not given)")
+	generateRootInitCmd.Flags().StringVar(&generateRootPGPKey, "pgp-key", "", "PGP public key file to encrypt the root token with")
+	generateRootSubmitCmd.Flags().StringVar(&generateRootOTP, "otp", "", "The OTP used to init the generation, to decode the root token with")
+
+}
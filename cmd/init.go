@@ -0,0 +1,385 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/jaxxstorm/hookpick/config"
+	"github.com/jaxxstorm/hookpick/gpg"
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+var (
+	initShares            int
+	initThreshold         int
+	initRecoveryShares    int
+	initRecoveryThreshold int
+	initPGPKeys           []string
+	initRecoveryPGPKeys   []string
+	initRootTokenPGPKey   string
+	initOutputDir         string
+	initInsecurePrintKeys bool
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:         "init",
+	Annotations: mutating,
+	Short:       "Initialises uninitialised Vault clusters",
+	Long: `Initialises every datacenter in the configuration file
+whose Vault servers have not been initialised yet. Exactly one
+host is initialised per datacenter, and the resulting keys and
+root token are written to the output directory. They are only
+printed if they are PGP encrypted, or --insecure-print-keys is set`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if initShares == 0 {
+			log.Fatal("Please specify the secret shares: See --help")
+		}
+
+		if initThreshold == 0 {
+			log.Fatal("Please specify the secret threshold: See --help")
+		}
+
+		if len(initPGPKeys) > 0 && len(initPGPKeys) != initShares {
+			log.Fatal("The number of PGP keys must match the secret shares: See --help")
+		}
+
+		if len(initRecoveryPGPKeys) > 0 && len(initRecoveryPGPKeys) != initRecoveryShares {
+			log.Fatal("The number of recovery PGP keys must match the recovery shares: See --help")
+		}
+
+		// without an output directory the response is printed. Which keys
+		// come back depends on the seal, so they are checked per host, but
+		// every cluster returns a root token.
+		if printsPlaintext() && initRootTokenPGPKey == "" {
+			log.Fatal("Please specify --output-dir, or --root-token-pgp-key to print an encrypted root token, or --insecure-print-keys to print it in plaintext: See --help")
+		}
+
+		pgpKeys, err := gpg.ReadPublicKeyFiles(initPGPKeys)
+		if err != nil {
+			log.Fatal("Error reading PGP keys: ", err)
+		}
+
+		recoveryPGPKeys, err := gpg.ReadPublicKeyFiles(initRecoveryPGPKeys)
+		if err != nil {
+			log.Fatal("Error reading recovery PGP keys: ", err)
+		}
+
+		var rootTokenPGPKey string
+		if initRootTokenPGPKey != "" {
+			rootTokenPGPKey, err = gpg.ReadPublicKeyFile(initRootTokenPGPKey)
+			if err != nil {
+				log.Fatal("Error reading root token PGP key: ", err)
+			}
+		}
+
+		initRequest := &api.InitRequest{
+			SecretShares:      initShares,
+			SecretThreshold:   initThreshold,
+			PGPKeys:           pgpKeys,
+			RecoveryShares:    initRecoveryShares,
+			RecoveryThreshold: initRecoveryThreshold,
+			RecoveryPGPKeys:   recoveryPGPKeys,
+			RootTokenPGPKey:   rootTokenPGPKey,
+		}
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			log.WithFields(log.Fields{
+				"datacenter": dc.Name,
+			}).Debugln("Starting to process Vault init")
+
+			go ProcessInit(&wg, dc, configHelper, v.NewVaultHelper, initRequest, InitHost, results)
+		}
+		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, false)
+	},
+}
+
+type HostInitImpl func(*v.VaultHelper, *api.InitRequest) (*api.InitResponse, error)
+
+func ProcessInit(wg *sync.WaitGroup,
+	dc config.Datacenter,
+	configHelper *ConfigHelper,
+	vhGetter v.VaultHelperGetter,
+	initRequest *api.InitRequest,
+	initHost HostInitImpl,
+	results *ResultSet) {
+
+	defer wg.Done()
+
+	caPath := configHelper.GetCAPath()
+
+	dcLogger := log.WithFields(log.Fields{"datacenter": dc.Name})
+	dcLogger.Debugln("Processing datacenter")
+
+	// every host in a datacenter shares the same storage, so if
+	// any of them is initialised the whole cluster is
+	var candidates []*v.VaultHelper
+	for _, host := range dc.Hosts {
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+
+		client, err := vaultHelper.GetVaultClient()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"port":  vaultHelper.Port,
+				"error": err,
+			}).Errorln("Error creating Vault API Client")
+			hostResult := NewHostResult(vaultHelper)
+			hostResult.SetError(err)
+			results.Add(hostResult)
+			continue
+		}
+
+		state, err := vaultHelper.GetStatus(client)
+		if err != nil {
+			dcLogger.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting vault status")
+			hostResult := NewHostResult(vaultHelper)
+			hostResult.SetUnreachable(err)
+			results.Add(hostResult)
+			continue
+		}
+
+		sealed, init := state.Sealed(), state.Initialized()
+		if init {
+			dcLogger.WithFields(log.Fields{
+				"host": vaultHelper.HostName,
+			}).Infoln("Vault is already initialised")
+			hostResult := NewHostResult(vaultHelper)
+			hostResult.Initialized = init
+			hostResult.Sealed = sealed
+			hostResult.Message = "Vault is already initialised"
+			results.Add(hostResult)
+			return
+		}
+
+		candidates = append(candidates, vaultHelper)
+	}
+
+	// only initialise a single host, falling through to the
+	// next candidate if it fails
+	for _, vaultHelper := range candidates {
+		hostResult := NewHostResult(vaultHelper)
+
+		result, err := initHost(vaultHelper, initRequest)
+		if err != nil {
+			dcLogger.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error initialising Vault")
+			hostResult.SetError(err)
+			results.Add(hostResult)
+			continue
+		}
+
+		dcLogger.WithFields(log.Fields{
+			"host": vaultHelper.HostName,
+		}).Infoln("Vault initialised")
+		hostResult.Initialized = true
+		hostResult.Message = "Vault initialised"
+
+		if err := storeInitResponse(dc.Name, vaultHelper.HostName, result); err != nil {
+			dcLogger.WithFields(log.Fields{
+				"error": err,
+			}).Errorln("Error storing init response")
+			hostResult.SetError(err)
+		}
+		results.Add(hostResult)
+		return
+	}
+
+	dcLogger.Errorln("Unable to initialise any host")
+}
+
+func InitHost(vaultHelper *v.VaultHelper, initRequest *api.InitRequest) (*api.InitResponse, error) {
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting init")
+
+	client, err := vaultHelper.GetVaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	sealStatus, err := client.Sys().SealStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	request := *initRequest
+
+	// auto-unseal seals only hand out recovery keys, the barrier
+	// key is stored by the seal itself
+	if sealStatus.RecoverySeal {
+		if request.RecoveryShares == 0 || request.RecoveryThreshold == 0 {
+			return nil, errors.New("Vault uses an auto-unseal seal, please specify the recovery shares and threshold: See --help")
+		}
+		request.SecretShares = 1
+		request.SecretThreshold = 1
+		request.StoredShares = 1
+		request.PGPKeys = nil
+	} else {
+		request.RecoveryShares = 0
+		request.RecoveryThreshold = 0
+		request.RecoveryPGPKeys = nil
+	}
+
+	if err := checkInitKeysEncrypted(sealStatus.RecoverySeal, &request); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"host":      vaultHelper.HostName,
+		"seal_type": sealStatus.Type,
+	}).Debugln("Sending init request")
+
+	return client.Sys().Init(&request)
+}
+
+// printsPlaintext checks if the init response would be printed without
+// being asked to
+func printsPlaintext() bool {
+	return initOutputDir == "" && !initInsecurePrintKeys
+}
+
+// checkInitKeysEncrypted refuses an init request whose keys would come back
+// unencrypted and be printed. Auto-unseal seals return recovery keys, shamir
+// seals return unseal keys.
+func checkInitKeysEncrypted(recoverySeal bool, request *api.InitRequest) error {
+
+	if !printsPlaintext() {
+		return nil
+	}
+
+	if recoverySeal && len(request.RecoveryPGPKeys) == 0 {
+		return errors.New("Vault uses an auto-unseal seal and the recovery keys would be printed in plaintext, please specify --output-dir, --recovery-pgp-keys or --insecure-print-keys: See --help")
+	}
+
+	if !recoverySeal && len(request.PGPKeys) == 0 {
+		return errors.New("the unseal keys would be printed in plaintext, please specify --output-dir, --pgp-keys or --insecure-print-keys: See --help")
+	}
+
+	return nil
+}
+
+type initOutput struct {
+	Datacenter      string   `json:"datacenter"`
+	Host            string   `json:"host"`
+	KeysB64         []string `json:"keys_base64,omitempty"`
+	RecoveryKeysB64 []string `json:"recovery_keys_base64,omitempty"`
+	RootToken       string   `json:"root_token"`
+}
+
+func storeInitResponse(dcName string, hostName string, result *api.InitResponse) error {
+
+	if initOutputDir != "" {
+		output := initOutput{
+			Datacenter:      dcName,
+			Host:            hostName,
+			KeysB64:         result.KeysB64,
+			RecoveryKeysB64: result.RecoveryKeysB64,
+			RootToken:       result.RootToken,
+		}
+
+		contents, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(initOutputDir, 0700); err != nil {
+			return err
+		}
+
+		path := filepath.Join(initOutputDir, dcName+"-init.json")
+		if err := ioutil.WriteFile(path, contents, 0600); err != nil {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"datacenter": dcName,
+			"path":       path,
+		}).Infoln("Init response written")
+
+		return nil
+	}
+
+	for _, key := range result.KeysB64 {
+		log.WithFields(log.Fields{
+			"datacenter": dcName,
+			"Key":        key,
+		}).Infoln("Unseal Key")
+	}
+
+	for _, key := range result.RecoveryKeysB64 {
+		log.WithFields(log.Fields{
+			"datacenter": dcName,
+			"Key":        key,
+		}).Infoln("Recovery Key")
+	}
+
+	log.WithFields(log.Fields{
+		"datacenter": dcName,
+		"Token":      result.RootToken,
+	}).Infoln("Initial Root Token")
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().IntVarP(&initShares, "shares", "s", 0, "The number of secret shares to split the master key into")
+	initCmd.Flags().IntVarP(&initThreshold, "threshold", "t", 0, "The number of secret shares required to reconstruct the master key")
+	initCmd.Flags().IntVar(&initRecoveryShares, "recovery-shares", 0, "The number of recovery shares to create, for auto-unseal seals")
+	initCmd.Flags().IntVar(&initRecoveryThreshold, "recovery-threshold", 0, "The number of recovery shares required, for auto-unseal seals")
+	initCmd.Flags().StringSliceVar(&initPGPKeys, "pgp-keys", nil, "Comma separated list of PGP public key files to encrypt the unseal keys with")
+	initCmd.Flags().StringSliceVar(&initRecoveryPGPKeys, "recovery-pgp-keys", nil, "Comma separated list of PGP public key files to encrypt the recovery keys with")
+	initCmd.Flags().StringVar(&initRootTokenPGPKey, "root-token-pgp-key", "", "PGP public key file to encrypt the initial root token with")
+	initCmd.Flags().StringVar(&initOutputDir, "output-dir", "", "Directory to write the init response to, one file per datacenter, instead of printing it")
+	initCmd.Flags().BoolVar(&initInsecurePrintKeys, "insecure-print-keys", false, "Print the keys and root token without --output-dir, even if they aren't PGP encrypted")
+
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestCheckInitKeysEncrypted(t *testing.T) {
+
+	defer func(outputDir string, insecure bool) {
+		initOutputDir, initInsecurePrintKeys = outputDir, insecure
+	}(initOutputDir, initInsecurePrintKeys)
+
+	pgpKeys := []string{"key-1", "key-2"}
+
+	tests := []struct {
+		name         string
+		outputDir    string
+		insecure     bool
+		recoverySeal bool
+		request      api.InitRequest
+		wantErr      bool
+	}{
+		{name: "shamir encrypted", request: api.InitRequest{PGPKeys: pgpKeys}},
+		{name: "shamir plaintext", request: api.InitRequest{RecoveryPGPKeys: pgpKeys}, wantErr: true},
+		{name: "auto-unseal encrypted", recoverySeal: true, request: api.InitRequest{RecoveryPGPKeys: pgpKeys}},
+		{name: "auto-unseal plaintext", recoverySeal: true, request: api.InitRequest{PGPKeys: pgpKeys}, wantErr: true},
+		{name: "output dir", outputDir: "keys", recoverySeal: true},
+		{name: "insecure", insecure: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initOutputDir, initInsecurePrintKeys = test.outputDir, test.insecure
+
+			err := checkInitKeysEncrypted(test.recoverySeal, &test.request)
+			if (err != nil) != test.wantErr {
+				t.Errorf("checkInitKeysEncrypted() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
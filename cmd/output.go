@@ -0,0 +1,316 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+// HostResult is the outcome of running a command against a single host
+type HostResult struct {
+	Datacenter  string `json:"datacenter" yaml:"datacenter"`
+	Host        string `json:"host" yaml:"host"`
+	Port        string `json:"port" yaml:"port"`
+	Sealed      bool   `json:"sealed" yaml:"sealed"`
+	Initialized bool   `json:"initialized" yaml:"initialized"`
+	Progress    int    `json:"progress" yaml:"progress"`
+	Threshold   int    `json:"threshold" yaml:"threshold"`
+	Unreachable bool   `json:"unreachable" yaml:"unreachable"`
+	Message     string `json:"message,omitempty" yaml:"message,omitempty"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Migration is set when vault reports a pending seal migration
+	Migration bool `json:"migration,omitempty" yaml:"migration,omitempty"`
+
+	// node details, only filled in by status
+	Version            string `json:"version,omitempty" yaml:"version,omitempty"`
+	ClusterName        string `json:"cluster_name,omitempty" yaml:"cluster_name,omitempty"`
+	ClusterID          string `json:"cluster_id,omitempty" yaml:"cluster_id,omitempty"`
+	SealType           string `json:"seal_type,omitempty" yaml:"seal_type,omitempty"`
+	HAEnabled          bool   `json:"ha_enabled,omitempty" yaml:"ha_enabled,omitempty"`
+	Role               string `json:"role,omitempty" yaml:"role,omitempty"`
+	LeaderAddress      string `json:"leader_address,omitempty" yaml:"leader_address,omitempty"`
+	RaftCommittedIndex uint64 `json:"raft_committed_index,omitempty" yaml:"raft_committed_index,omitempty"`
+
+	// disagreements with the rest of the datacenter, only filled in by status --consistency
+	Inconsistencies []string `json:"inconsistencies,omitempty" yaml:"inconsistencies,omitempty"`
+
+	// raft peer details, only filled in by raft status
+	Raft *RaftPeer `json:"raft,omitempty" yaml:"raft,omitempty"`
+
+	// the decoded root token, only filled in by generate-root submit
+	RootToken string `json:"root_token,omitempty" yaml:"root_token,omitempty"`
+}
+
+// RaftPeer is a host's place in the raft peer set
+type RaftPeer struct {
+	NodeID      string `json:"node_id,omitempty" yaml:"node_id,omitempty"`
+	Address     string `json:"address,omitempty" yaml:"address,omitempty"`
+	Leader      bool   `json:"leader" yaml:"leader"`
+	Voter       bool   `json:"voter" yaml:"voter"`
+	Healthy     *bool  `json:"healthy,omitempty" yaml:"healthy,omitempty"`
+	NodeStatus  string `json:"node_status,omitempty" yaml:"node_status,omitempty"`
+	LastContact string `json:"last_contact,omitempty" yaml:"last_contact,omitempty"`
+	InRaft      bool   `json:"in_raft" yaml:"in_raft"`
+	InConfig    bool   `json:"in_config" yaml:"in_config"`
+}
+
+// SetNodeInfo copies the node details into the result
+func (r *HostResult) SetNodeInfo(info *v.NodeInfo) {
+	r.Version = info.Version
+	r.ClusterName = info.ClusterName
+	r.ClusterID = info.ClusterID
+	r.SealType = info.SealType
+	r.HAEnabled = info.HAEnabled
+	r.Role = info.Role
+	r.LeaderAddress = info.LeaderAddress
+	r.RaftCommittedIndex = info.RaftCommittedIndex
+}
+
+// NewHostResult returns a result for the host the helper points at
+func NewHostResult(vaultHelper *v.VaultHelper) HostResult {
+	return HostResult{
+		Datacenter: vaultHelper.Datacenter,
+		Host:       vaultHelper.HostName,
+		Port:       vaultHelper.Port,
+	}
+}
+
+// SetError records err against the result, if there is one
+func (r *HostResult) SetError(err error) {
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// SetUnreachable records that the host could not be reached
+func (r *HostResult) SetUnreachable(err error) {
+	r.Unreachable = true
+	r.SetError(err)
+}
+
+// ResultSet collects the results from every host a command ran against
+type ResultSet struct {
+	sync.Mutex
+	Results []HostResult
+}
+
+// Add records the result for a host. It is safe to call on a nil ResultSet.
+func (r *ResultSet) Add(result HostResult) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.Results = append(r.Results, result)
+}
+
+// AddIfReported records the result only if the host did something or failed,
+// so that operations which only run on the leader don't report every standby
+func (r *ResultSet) AddIfReported(result HostResult) {
+	if result.Message != "" || result.Error != "" {
+		r.Add(result)
+	}
+}
+
+// Sorted returns a copy of the results ordered by datacenter, host and port
+func (r *ResultSet) Sorted() []HostResult {
+	r.Lock()
+	defer r.Unlock()
+
+	sorted := make([]HostResult, len(r.Results))
+	copy(sorted, r.Results)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Datacenter != sorted[j].Datacenter {
+			return sorted[i].Datacenter < sorted[j].Datacenter
+		}
+		if sorted[i].Host != sorted[j].Host {
+			return sorted[i].Host < sorted[j].Host
+		}
+		return sorted[i].Port < sorted[j].Port
+	})
+
+	return sorted
+}
+
+// validOutputFormat checks the --output flag
+func validOutputFormat(format string) bool {
+	switch format {
+	case "", "json", "yaml", "table":
+		return true
+	}
+	return false
+}
+
+// RenderResults writes the results to w in the given format
+func RenderResults(w io.Writer, format string, results []HostResult) error {
+
+	// always render a list, even if nothing was collected
+	if results == nil {
+		results = []HostResult{}
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case "yaml":
+		contents, err := yaml.Marshal(results)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(contents)
+		return err
+	case "table":
+		return renderTable(w, results)
+	}
+
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// tableColumn is a single column of the results table. Optional columns are
+// left out when no result has a value for them.
+type tableColumn struct {
+	header   string
+	optional bool
+	value    func(HostResult) string
+}
+
+var tableColumns = []tableColumn{
+	{header: "DATACENTER", value: func(r HostResult) string { return r.Datacenter }},
+	{header: "HOST", value: func(r HostResult) string { return r.Host }},
+	{header: "PORT", value: func(r HostResult) string { return r.Port }},
+	{header: "INITIALIZED", value: func(r HostResult) string { return strconv.FormatBool(r.Initialized) }},
+	{header: "SEALED", value: func(r HostResult) string { return strconv.FormatBool(r.Sealed) }},
+	{header: "PROGRESS", value: func(r HostResult) string { return fmt.Sprintf("%d/%d", r.Progress, r.Threshold) }},
+	{header: "MIGRATION", optional: true, value: func(r HostResult) string {
+		if !r.Migration {
+			return ""
+		}
+		return strconv.FormatBool(r.Migration)
+	}},
+	{header: "ROLE", optional: true, value: func(r HostResult) string { return r.Role }},
+	{header: "VERSION", optional: true, value: func(r HostResult) string { return r.Version }},
+	{header: "SEAL TYPE", optional: true, value: func(r HostResult) string { return r.SealType }},
+	{header: "HA", optional: true, value: func(r HostResult) string {
+		if r.Role == "" || r.Role == v.RoleSealed {
+			return ""
+		}
+		return strconv.FormatBool(r.HAEnabled)
+	}},
+	{header: "LEADER", optional: true, value: func(r HostResult) string { return r.LeaderAddress }},
+	{header: "RAFT INDEX", optional: true, value: func(r HostResult) string {
+		if r.RaftCommittedIndex == 0 {
+			return ""
+		}
+		return strconv.FormatUint(r.RaftCommittedIndex, 10)
+	}},
+	{header: "NODE ID", optional: true, value: func(r HostResult) string { return raftValue(r, func(p *RaftPeer) string { return p.NodeID }) }},
+	{header: "RAFT ADDRESS", optional: true, value: func(r HostResult) string { return raftValue(r, func(p *RaftPeer) string { return p.Address }) }},
+	{header: "VOTER", optional: true, value: func(r HostResult) string {
+		return raftValue(r, func(p *RaftPeer) string { return strconv.FormatBool(p.Voter) })
+	}},
+	{header: "RAFT HEALTHY", optional: true, value: func(r HostResult) string {
+		return raftValue(r, func(p *RaftPeer) string {
+			if p.Healthy == nil {
+				return ""
+			}
+			return strconv.FormatBool(*p.Healthy)
+		})
+	}},
+	{header: "LAST CONTACT", optional: true, value: func(r HostResult) string { return raftValue(r, func(p *RaftPeer) string { return p.LastContact }) }},
+	{header: "CLUSTER", optional: true, value: func(r HostResult) string { return r.ClusterName }},
+	{header: "CLUSTER ID", optional: true, value: func(r HostResult) string { return r.ClusterID }},
+	{header: "INCONSISTENCIES", optional: true, value: func(r HostResult) string { return strings.Join(r.Inconsistencies, "; ") }},
+	{header: "ROOT TOKEN", optional: true, value: func(r HostResult) string { return r.RootToken }},
+	{header: "MESSAGE", value: func(r HostResult) string { return r.Message }},
+	{header: "ERROR", value: func(r HostResult) string { return r.Error }},
+}
+
+// raftValue returns the value of a raft column, or nothing for hosts that
+// aren't in the raft peer set
+func raftValue(r HostResult, value func(*RaftPeer) string) string {
+	if r.Raft == nil || !r.Raft.InRaft {
+		return ""
+	}
+	return value(r.Raft)
+}
+
+func renderTable(w io.Writer, results []HostResult) error {
+
+	var columns []tableColumn
+	for _, column := range tableColumns {
+		if !column.optional {
+			columns = append(columns, column)
+			continue
+		}
+		for _, result := range results {
+			if column.value(result) != "" {
+				columns = append(columns, column)
+				break
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	var headers []string
+	for _, column := range columns {
+		headers = append(headers, column.header)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, result := range results {
+		var values []string
+		for _, column := range columns {
+			values = append(values, column.value(result))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// renderResults prints the collected results to stdout if --output was given
+func renderResults(results *ResultSet) {
+
+	if outputFormat == "" {
+		return
+	}
+
+	if err := RenderResults(os.Stdout, outputFormat, results.Sorted()); err != nil {
+		log.Fatal("Error rendering output: ", err)
+	}
+}
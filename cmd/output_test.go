@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+var sampleResults = []HostResult{
+	{Datacenter: "dc1", Host: "vault-1", Port: "8200", Initialized: true, Threshold: 3, Message: msgAlreadyUnsealed},
+	{Datacenter: "dc1", Host: "vault-2", Port: "8200", Initialized: true, Sealed: true, Progress: 1, Threshold: 3, Migration: true},
+	{Datacenter: "dc1", Host: "vault-3", Port: "8200", Unreachable: true, Error: "connection refused"},
+}
+
+func TestRenderResultsJSON(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := RenderResults(&buf, "json", sampleResults); err != nil {
+		t.Fatalf("RenderResults() error = %v", err)
+	}
+
+	var got []HostResult
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("RenderResults() wrote invalid json: %v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(got, sampleResults) {
+		t.Errorf("RenderResults() round trip =\n%+v\nwant\n%+v", got, sampleResults)
+	}
+
+	// optional fields are only written for the hosts that have them
+	var hosts []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &hosts); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []struct {
+		present, absent []string
+	}{
+		{present: []string{"message"}, absent: []string{"error", "migration", "role", "raft", "root_token"}},
+		{present: []string{"migration"}, absent: []string{"message", "error"}},
+		{present: []string{"error", "unreachable"}, absent: []string{"message", "migration"}},
+	} {
+		for _, key := range want.present {
+			if _, ok := hosts[i][key]; !ok {
+				t.Errorf("RenderResults() host %d is missing %q: %v", i, key, hosts[i])
+			}
+		}
+		for _, key := range want.absent {
+			if _, ok := hosts[i][key]; ok {
+				t.Errorf("RenderResults() host %d has %q: %v", i, key, hosts[i])
+			}
+		}
+	}
+}
+
+func TestRenderResultsYAML(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := RenderResults(&buf, "yaml", sampleResults); err != nil {
+		t.Fatalf("RenderResults() error = %v", err)
+	}
+
+	var got []HostResult
+	if err := yaml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("RenderResults() wrote invalid yaml: %v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(got, sampleResults) {
+		t.Errorf("RenderResults() round trip =\n%+v\nwant\n%+v", got, sampleResults)
+	}
+
+	for _, line := range []string{"  migration: true", "  error: connection refused"} {
+		if !containsLine(strings.Split(buf.String(), "\n"), line) {
+			t.Errorf("RenderResults() is missing %q in\n%s", line, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "migration: false") {
+		t.Errorf("RenderResults() wrote an unset migration:\n%s", buf.String())
+	}
+}
+
+func TestRenderResultsEmpty(t *testing.T) {
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", "[]\n"},
+		{"yaml", "[]\n"},
+		{"table", "DATACENTER  HOST  PORT  INITIALIZED  SEALED  PROGRESS  MESSAGE  ERROR\n"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := RenderResults(&buf, test.format, nil); err != nil {
+			t.Errorf("RenderResults(%s) error = %v", test.format, err)
+			continue
+		}
+		if buf.String() != test.want {
+			t.Errorf("RenderResults(%s) = %q, want %q", test.format, buf.String(), test.want)
+		}
+	}
+}
+
+func TestRenderResultsUnknownFormat(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := RenderResults(&buf, "xml", sampleResults); err == nil {
+		t.Error("RenderResults(xml) error = nil, want an error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("RenderResults(xml) wrote %q, want nothing", buf.String())
+	}
+}
+
+func TestRenderTableColumns(t *testing.T) {
+
+	healthy := true
+	base := []string{"DATACENTER", "HOST", "PORT", "INITIALIZED", "SEALED", "PROGRESS"}
+	trailer := []string{"MESSAGE", "ERROR"}
+
+	tests := []struct {
+		name    string
+		results []HostResult
+		want    []string
+	}{
+		{
+			name:    "required only",
+			results: []HostResult{{Host: "vault-1"}},
+		},
+		{
+			name:    "migration",
+			results: []HostResult{{Host: "vault-1"}, {Host: "vault-2", Migration: true}},
+			want:    []string{"MIGRATION"},
+		},
+		{
+			name: "node details",
+			results: []HostResult{{
+				Host:               "vault-1",
+				Role:               v.RoleActive,
+				Version:            "1.3.1",
+				SealType:           "shamir",
+				HAEnabled:          true,
+				LeaderAddress:      "https://vault-1:8200",
+				RaftCommittedIndex: 42,
+				ClusterName:        "vault-cluster",
+				ClusterID:          "1234",
+			}},
+			want: []string{"ROLE", "VERSION", "SEAL TYPE", "HA", "LEADER", "RAFT INDEX", "CLUSTER", "CLUSTER ID"},
+		},
+		{
+			name:    "sealed role has no ha",
+			results: []HostResult{{Host: "vault-1", Role: v.RoleSealed}},
+			want:    []string{"ROLE"},
+		},
+		{
+			name: "raft peer",
+			results: []HostResult{{
+				Host: "vault-1",
+				Raft: &RaftPeer{NodeID: "node-1", Address: "vault-1:8201", Voter: true, Healthy: &healthy, LastContact: "1s", InRaft: true},
+			}},
+			want: []string{"NODE ID", "RAFT ADDRESS", "VOTER", "RAFT HEALTHY", "LAST CONTACT"},
+		},
+		{
+			name:    "not in raft",
+			results: []HostResult{{Host: "vault-1", Raft: &RaftPeer{NodeID: "node-1", InConfig: true}}},
+		},
+		{
+			name:    "inconsistencies",
+			results: []HostResult{{Host: "vault-1", Inconsistencies: []string{"version 1.3.1, most have 1.3.2"}}},
+			want:    []string{"INCONSISTENCIES"},
+		},
+		{
+			name:    "root token",
+			results: []HostResult{{Host: "vault-1", RootToken: "s.token"}},
+			want:    []string{"ROOT TOKEN"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderTable(&buf, test.results); err != nil {
+				t.Fatalf("renderTable() error = %v", err)
+			}
+
+			want := append(append(append([]string{}, base...), test.want...), trailer...)
+			lines := strings.Split(buf.String(), "\n")
+			if got := tableCells(lines[0]); !reflect.DeepEqual(got, want) {
+				t.Errorf("renderTable() headers = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRenderTableRows(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := renderTable(&buf, sampleResults); err != nil {
+		t.Fatalf("renderTable() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != len(sampleResults)+1 {
+		t.Fatalf("renderTable() printed %d lines, want a header and %d rows:\n%s", len(lines), len(sampleResults), buf.String())
+	}
+
+	tests := []struct {
+		row    int
+		column string
+		want   string
+	}{
+		{1, "HOST", "vault-1"},
+		{1, "PROGRESS", "0/3"},
+		{1, "MIGRATION", ""},
+		{1, "MESSAGE", msgAlreadyUnsealed},
+		{1, "ERROR", ""},
+		{2, "SEALED", "true"},
+		{2, "PROGRESS", "1/3"},
+		{2, "MIGRATION", "true"},
+		{3, "INITIALIZED", "false"},
+		{3, "MESSAGE", ""},
+		{3, "ERROR", "connection refused"},
+	}
+
+	for _, test := range tests {
+		if got := tableCell(lines[0], lines[test.row], test.column); got != test.want {
+			t.Errorf("renderTable() row %d %s = %q, want %q", test.row, test.column, got, test.want)
+		}
+	}
+}
+
+func TestValidOutputFormat(t *testing.T) {
+
+	for _, format := range []string{"", "json", "yaml", "table"} {
+		if !validOutputFormat(format) {
+			t.Errorf("validOutputFormat(%q) = false, want true", format)
+		}
+	}
+	for _, format := range []string{"xml", "JSON", "text"} {
+		if validOutputFormat(format) {
+			t.Errorf("validOutputFormat(%q) = true, want false", format)
+		}
+	}
+}
+
+var tableGap = regexp.MustCompile(`\s{2,}`)
+
+// tableCells splits a header line into its column names
+func tableCells(line string) []string {
+	return tableGap.Split(strings.TrimSpace(line), -1)
+}
+
+// tableCell returns the value in row under the named column, using the
+// position of the column in the header line
+func tableCell(header, row, column string) string {
+	start := strings.Index(header, column)
+	if start < 0 || start >= len(row) {
+		return ""
+	}
+	end := len(row)
+	if next := tableGap.FindStringIndex(header[start:]); next != nil {
+		end = start + next[1]
+		if end > len(row) {
+			end = len(row)
+		}
+	}
+	return strings.TrimSpace(row[start:end])
+}
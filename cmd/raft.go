@@ -0,0 +1,272 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/jaxxstorm/hookpick/config"
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+// raftCmd represents the raft command
+var raftCmd = &cobra.Command{
+	Use:   "raft",
+	Short: "Inspect integrated storage (raft) clusters",
+	Long: `Commands for inspecting Vault clusters using integrated
+storage (raft)`,
+}
+
+var raftStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the raft peers and their health",
+	Long: `Reads the raft configuration and autopilot state from the
+active node in each datacenter and shows every peer's voter status,
+health and last contact. Hosts in the configuration file that are not
+raft peers, and raft peers that are not in the configuration file, are
+reported as errors. This requires a token`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		vaultToken, err := GetToken()
+		if err != nil {
+			log.Fatal("Error reading token: ", err)
+		}
+
+		if vaultToken == "" {
+			log.Fatal("Reading the raft configuration requires a token: See --help")
+		}
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			log.WithFields(log.Fields{
+				"datacenter": dc.Name,
+			}).Debugln("Starting to process raft status")
+
+			go ProcessRaftStatus(&wg, dc, configHelper, v.NewVaultHelper, vaultToken, HostRaftStatus, results)
+		}
+		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, false)
+	},
+}
+
+type HostRaftImpl func(*v.VaultHelper) (*v.RaftConfiguration, *v.AutopilotState, error)
+
+func ProcessRaftStatus(wg *sync.WaitGroup,
+	dc config.Datacenter,
+	configHelper *ConfigHelper,
+	vhGetter v.VaultHelperGetter,
+	vaultToken string,
+	raftStatusHost HostRaftImpl,
+	results *ResultSet) {
+
+	defer wg.Done()
+
+	caPath := configHelper.GetCAPath()
+
+	dcLogger := log.WithFields(log.Fields{"datacenter": dc.Name})
+	dcLogger.Debugln("Processing datacenter")
+
+	var vaultHelpers []*v.VaultHelper
+	for _, host := range dc.Hosts {
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		vaultHelper.Token = vaultToken
+		vaultHelpers = append(vaultHelpers, vaultHelper)
+	}
+
+	leader := findActiveNode(vaultHelpers)
+	if leader == nil {
+		dcLogger.Errorln("Unable to find the active node")
+		results.Add(HostResult{Datacenter: dc.Name, Error: "Unable to find the active node"})
+		return
+	}
+
+	raftConfig, autopilot, err := raftStatusHost(leader)
+	if err != nil {
+		dcLogger.WithFields(log.Fields{
+			"host":  leader.HostName,
+			"error": err,
+		}).Errorln("Error reading raft status")
+		hostResult := NewHostResult(leader)
+		hostResult.SetError(err)
+		results.Add(hostResult)
+		return
+	}
+
+	if raftConfig == nil {
+		dcLogger.WithFields(log.Fields{
+			"host": leader.HostName,
+		}).Infoln("Raft storage is not in use")
+		hostResult := NewHostResult(leader)
+		hostResult.Initialized = true
+		hostResult.Message = "Raft storage is not in use"
+		results.Add(hostResult)
+		return
+	}
+
+	if autopilot != nil {
+		dcLogger.WithFields(log.Fields{
+			"healthy":           autopilot.Healthy,
+			"failure_tolerance": autopilot.FailureTolerance,
+			"leader":            autopilot.Leader,
+		}).Infoln("Autopilot state")
+	} else {
+		dcLogger.Warnln("Autopilot state is not available, peer health is unknown")
+	}
+
+	matched := make(map[int]bool)
+	for _, server := range raftConfig.Servers {
+		hostResult := HostResult{
+			Datacenter:  dc.Name,
+			Host:        raftPeerHost(server.Address),
+			Initialized: true,
+			Raft: &RaftPeer{
+				NodeID:  server.NodeID,
+				Address: server.Address,
+				Leader:  server.Leader,
+				Voter:   server.Voter,
+				InRaft:  true,
+			},
+		}
+
+		for i, host := range dc.Hosts {
+			if matchRaftPeer(host, server) {
+				matched[i] = true
+				hostResult.Host = host.Name
+				hostResult.Port = host.Port
+				hostResult.Raft.InConfig = true
+				break
+			}
+		}
+
+		if autopilot != nil {
+			if state, ok := autopilot.Servers[server.NodeID]; ok {
+				healthy := state.Healthy
+				hostResult.Raft.Healthy = &healthy
+				hostResult.Raft.NodeStatus = state.NodeStatus
+				hostResult.Raft.LastContact = state.LastContact
+			}
+		}
+
+		peerLogger := dcLogger.WithFields(log.Fields{
+			"host":         hostResult.Host,
+			"node_id":      server.NodeID,
+			"address":      server.Address,
+			"leader":       server.Leader,
+			"voter":        server.Voter,
+			"node_status":  hostResult.Raft.NodeStatus,
+			"last_contact": hostResult.Raft.LastContact,
+		})
+
+		switch {
+		case !hostResult.Raft.InConfig:
+			peerLogger.Errorln("Raft peer is not in the configuration file")
+			hostResult.Error = "Raft peer is not in the configuration file"
+		case hostResult.Raft.Healthy != nil && !*hostResult.Raft.Healthy:
+			peerLogger.Errorln("Raft peer is unhealthy")
+			hostResult.Error = "Raft peer is unhealthy"
+		default:
+			peerLogger.Infoln("Raft peer")
+			hostResult.Message = "Raft peer"
+		}
+
+		results.Add(hostResult)
+	}
+
+	for i, host := range dc.Hosts {
+		if matched[i] {
+			continue
+		}
+		dcLogger.WithFields(log.Fields{
+			"host": host.Name,
+		}).Errorln("Host is not a raft peer")
+		results.Add(HostResult{
+			Datacenter: dc.Name,
+			Host:       host.Name,
+			Port:       host.Port,
+			Raft:       &RaftPeer{InConfig: true},
+			Error:      "Host is not a raft peer",
+		})
+	}
+}
+
+func HostRaftStatus(vaultHelper *v.VaultHelper) (*v.RaftConfiguration, *v.AutopilotState, error) {
+
+	client, err := vaultHelper.GetVaultClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raftConfig, err := v.GetRaftConfiguration(client)
+	if err != nil || raftConfig == nil {
+		return nil, nil, err
+	}
+
+	// autopilot is only available on newer versions of vault
+	autopilot, err := v.GetAutopilotState(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Warnln("Error reading autopilot state")
+	}
+
+	return raftConfig, autopilot, nil
+}
+
+// raftPeerHost strips the port from a raft cluster address
+func raftPeerHost(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
+
+// matchRaftPeer checks if a configured host is the given raft peer, either
+// by the host of its cluster address or its node ID
+func matchRaftPeer(host config.Host, server v.RaftServer) bool {
+	peerHost := raftPeerHost(server.Address)
+	shortName := strings.SplitN(host.Name, ".", 2)[0]
+
+	return strings.EqualFold(host.Name, peerHost) ||
+		strings.EqualFold(host.Name, server.NodeID) ||
+		strings.EqualFold(shortName, server.NodeID)
+}
+
+func init() {
+	RootCmd.AddCommand(raftCmd)
+	raftCmd.AddCommand(raftStatusCmd)
+}
@@ -21,6 +21,13 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -36,6 +43,17 @@ import (
 
 var shares int
 var threshold int
+var requireVerification bool
+var rekeyPGPKeys []string
+var insecurePrintKeys bool
+var rekeyOutputDir string
+var rekeyEncodedPGPKeys []string
+var rekeyBackup bool
+var recoveryKeys bool
+var rekeyKeyFiles []string
+
+// rekeyPlaintextKeyFiles : the --key-file files hold plaintext keys
+var rekeyPlaintextKeyFiles bool
 
 // rekeyCmd represents the rekey command
 var rekeyCmd = &cobra.Command{
@@ -45,9 +63,10 @@ var rekeyCmd = &cobra.Command{
 or specified Vault servers in the configuration file`,
 }
 
-var initCmd = &cobra.Command{
-	Use:   "init",
-	Short: "Starts the rekey operation on specified Vault server",
+var rekeyInitCmd = &cobra.Command{
+	Use:         "init",
+	Annotations: mutating,
+	Short:       "Starts the rekey operation on specified Vault server",
 	Long: `Initialises a rekey against specified Vault servers
 and returns the client nonce needed for other rekey operators`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -60,36 +79,63 @@ and returns the client nonce needed for other rekey operators`,
 			log.Fatal("Please specify the secret shares: See --help")
 		}
 
-		allDCs := GetDatacenters()
-		configHelper := NewConfigHelper(GetSpecificDatacenter, GetCaPath, GetProtocol, GetGpgKey)
+		if len(rekeyPGPKeys) == 0 && !insecurePrintKeys {
+			log.Fatal("Please specify --pgp-keys to encrypt the new keys, or --insecure-print-keys to print them in plaintext: See --help")
+		}
+
+		if rekeyBackup && len(rekeyPGPKeys) == 0 {
+			log.Fatal("Backing up the new keys requires --pgp-keys: See --help")
+		}
+
+		if len(rekeyPGPKeys) > 0 && len(rekeyPGPKeys) != shares {
+			log.Fatal("The number of PGP keys must match the secret shares: See --help")
+		}
+
+		var err error
+		rekeyEncodedPGPKeys, err = gpg.ReadPublicKeyFiles(rekeyPGPKeys)
+		if err != nil {
+			log.Fatal("Error reading PGP keys: ", err)
+		}
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
 
 		wg := sync.WaitGroup{}
+		results := &ResultSet{}
 		// loop through datacenters
 		for _, dc := range allDCs {
 			wg.Add(1)
-			go ProcessRekey(&wg, dc, configHelper, v.NewVaultHelper, HostRekeyInit)
+			go ProcessRekey(&wg, dc, configHelper, v.NewVaultHelper, HostRekeyInit, results)
 		}
 		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, true)
 	},
 }
 
-var submitCmd = &cobra.Command{
-	Use:   "submit",
-	Short: "Submits your key to the rekey command",
+var rekeySubmitCmd = &cobra.Command{
+	Use:         "submit",
+	Annotations: mutating,
+	Short:       "Submits your key to the rekey command",
 	Long: `Submits your unseal key to the rekey process
 and progresses the rekey`,
 	Run: func(cmd *cobra.Command, args []string) {
-		allDCs := GetDatacenters()
-		configHelper := NewConfigHelper(GetSpecificDatacenter, GetCaPath, GetProtocol, GetGpgKey)
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
 		gpgHelper := gpg.NewGPGHelper(gpg.Decrypt)
 
 		wg := sync.WaitGroup{}
+		results := &ResultSet{}
 
 		for _, dc := range allDCs {
 			wg.Add(1)
-			go ProcessRekeySubmit(&wg, dc, configHelper, v.NewVaultHelper, gpgHelper, GetVaultKeys, HostRekeySubmit)
+			go ProcessRekeySubmit(&wg, dc, configHelper, v.NewVaultHelper, gpgHelper, GetVaultKeys, HostRekeySubmit, results)
 		}
 		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, true)
 	},
 }
 
@@ -100,19 +146,136 @@ var rekeyStatusCmd = &cobra.Command{
 from all the specified Vault servers`,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		allDCs := GetDatacenters()
-		configHelper := NewConfigHelper(GetSpecificDatacenter, GetCaPath, GetProtocol, GetGpgKey)
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
 
 		wg := sync.WaitGroup{}
+		results := &ResultSet{}
 
 		for _, dc := range allDCs {
 			wg.Add(1)
 			log.WithFields(log.Fields{
 				"datacenter": dc.Name,
 			}).Debugln("Starting to process rekey")
-			go ProcessRekey(&wg, dc, configHelper, v.NewVaultHelper, HostRekeyStatus)
+			go ProcessRekey(&wg, dc, configHelper, v.NewVaultHelper, HostRekeyStatus, results)
+		}
+		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, true)
+	},
+}
+
+var rekeyVerifyCmd = &cobra.Command{
+	Use:         "verify",
+	Annotations: mutating,
+	Short:       "Submits your new key to verify a rekey",
+	Long: `Submits the new unseal keys from a rekey started with
+--require-verification, proving the new keys work before
+they replace the old ones. The new keys are read from the
+files given with --key-file, such as those written by
+rekey submit, and are decrypted with gpg unless
+--plaintext-key-files is set`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(rekeyKeyFiles) == 0 {
+			log.Fatal("Verifying a rekey requires the new keys: See --help")
+		}
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+		gpgHelper := gpg.NewGPGHelper(gpg.Decrypt)
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			go ProcessRekeySubmit(&wg, dc, configHelper, v.NewVaultHelper, gpgHelper, GetNewVaultKeys, HostRekeyVerify, results)
+		}
+		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, true)
+	},
+}
+
+var rekeyBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manages the PGP encrypted backup of rekeyed keys",
+	Long: `Retrieves or deletes the PGP encrypted copies of the new keys
+that Vault stores when a rekey is started with --backup`,
+}
+
+var rekeyBackupRetrieveCmd = &cobra.Command{
+	Use:   "retrieve",
+	Short: "Retrieves the backed up keys",
+	Long: `Retrieves the PGP encrypted backup of the new keys from the leader
+of all the specified Vault servers and writes them to disk, one file
+per PGP fingerprint. This requires a token`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runRekeyBackup(HostRekeyBackupRetrieve)
+	},
+}
+
+var rekeyBackupDeleteCmd = &cobra.Command{
+	Use:         "delete",
+	Annotations: mutating,
+	Short:       "Deletes the backed up keys",
+	Long: `Deletes the PGP encrypted backup of the new keys from the leader
+of all the specified Vault servers. This requires a token`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runRekeyBackup(HostRekeyBackupDelete)
+	},
+}
+
+func runRekeyBackup(hostRekeyBackup HostImpl) {
+	vaultToken, err := GetToken()
+	if err != nil {
+		log.Fatal("Error reading token: ", err)
+	}
+
+	if vaultToken == "" {
+		log.Fatal("Managing rekey backups requires a token: See --help")
+	}
+
+	allDCs := GetTargetDatacenters()
+	configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+	wg := sync.WaitGroup{}
+	results := &ResultSet{}
+
+	for _, dc := range allDCs {
+		wg.Add(1)
+		go ProcessRekeyBackup(&wg, dc, configHelper, v.NewVaultHelper, vaultToken, hostRekeyBackup, results)
+	}
+	wg.Wait()
+
+	renderResults(results)
+	exitWithResults(results, true)
+}
+
+var rekeyCancelCmd = &cobra.Command{
+	Use:         "cancel",
+	Annotations: mutating,
+	Short:       "Cancels an in progress rekey",
+	Long: `Cancels any in progress rekey on the leader
+of all the specified Vault servers`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			go ProcessRekey(&wg, dc, configHelper, v.NewVaultHelper, HostRekeyCancel, results)
 		}
 		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, true)
 	},
 }
 
@@ -120,31 +283,58 @@ func ProcessRekey(wg *sync.WaitGroup,
 	dc config.Datacenter,
 	configHelper *ConfigHelper,
 	vhGetter v.VaultHelperGetter,
-	hostRekeyInit HostImpl) {
+	hostRekeyInit HostImpl,
+	results *ResultSet) {
 	defer wg.Done()
 
-	specificDC := configHelper.GetDC()
 	caPath := configHelper.GetCAPath()
-	protocol := configHelper.GetURLScheme()
 
 	log.WithFields(log.Fields{
 		"datacenter": dc.Name,
-		"dc":         specificDC,
 	}).Debugln("Processing rekey for")
 
-	if specificDC == dc.Name || specificDC == "" {
+	hwg := sync.WaitGroup{}
+	for _, host := range dc.Hosts {
+		hwg.Add(1)
+		log.WithFields(log.Fields{
+			"host": host.Name,
+		}).Debugln("Starting to process rekey")
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		go hostRekeyInit(&hwg, vaultHelper, results)
+	}
+	hwg.Wait()
+}
 
-		hwg := sync.WaitGroup{}
-		for _, host := range dc.Hosts {
-			hwg.Add(1)
-			log.WithFields(log.Fields{
-				"host": host.Name,
-			}).Debugln("Starting to process rekey")
-			vaultHelper := vhGetter(host.Name, caPath, protocol, host.Port, v.Status)
-			go hostRekeyInit(&hwg, vaultHelper)
-		}
-		hwg.Wait()
+func ProcessRekeyBackup(wg *sync.WaitGroup,
+	dc config.Datacenter,
+	configHelper *ConfigHelper,
+	vhGetter v.VaultHelperGetter,
+	vaultToken string,
+	hostRekeyBackup HostImpl,
+	results *ResultSet) {
+	defer wg.Done()
+
+	caPath := configHelper.GetCAPath()
+
+	log.WithFields(log.Fields{
+		"datacenter": dc.Name,
+	}).Debugln("Processing rekey backup for")
+
+	hwg := sync.WaitGroup{}
+	for _, host := range dc.Hosts {
+		hwg.Add(1)
+		log.WithFields(log.Fields{
+			"host": host.Name,
+		}).Debugln("Starting to process rekey backup")
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		vaultHelper.Token = vaultToken
+		go hostRekeyBackup(&hwg, vaultHelper, results)
 	}
+	hwg.Wait()
 }
 
 func ProcessRekeySubmit(wg *sync.WaitGroup,
@@ -153,38 +343,39 @@ func ProcessRekeySubmit(wg *sync.WaitGroup,
 	vhGetter v.VaultHelperGetter,
 	gpgHelper *gpg.GPGHelper,
 	vaultKeysGetter VaultKeyGetter,
-	submitHostRekey HostSubmitImpl) {
+	submitHostRekey HostSubmitImpl,
+	results *ResultSet) {
 	defer wg.Done()
 
-	specificDC := configHelper.GetDC()
 	caPath := configHelper.GetCAPath()
-	protocol := configHelper.GetURLScheme()
 
 	log.WithFields(log.Fields{
 		"datacenter": dc.Name,
-		"dc":         specificDC,
 	}).Debugln("Processing rekey for")
 
-	if specificDC == dc.Name || specificDC == "" {
+	vaultKeys := vaultKeysGetter(dc, configHelper.GetGPGKey, gpgHelper.Decrypt)
 
-		vaultKeys := vaultKeysGetter(dc, configHelper.GetGPGKey, gpgHelper.Decrypt)
-
-		hwg := sync.WaitGroup{}
-		for _, host := range dc.Hosts {
-			hwg.Add(1)
-			log.WithFields(log.Fields{
-				"host": host.Name,
-			}).Debugln("Starting to process rekey")
+	hwg := sync.WaitGroup{}
+	for _, host := range dc.Hosts {
+		hwg.Add(1)
+		log.WithFields(log.Fields{
+			"host": host.Name,
+		}).Debugln("Starting to process rekey")
 
-			vaultHelper := vhGetter(host.Name, caPath, protocol, host.Port, v.Status)
-			go submitHostRekey(&hwg, vaultHelper, vaultKeys)
-		}
-		hwg.Wait()
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		go submitHostRekey(&hwg, vaultHelper, vaultKeys, results)
 	}
+	hwg.Wait()
 }
 
-func HostRekeyInit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
+func HostRekeyInit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
 	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
 	client, err := vaultHelper.GetVaultClient()
 
 	if err != nil {
@@ -192,6 +383,8 @@ func HostRekeyInit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
 			"host": vaultHelper.HostName,
 			"port": vaultHelper.Port,
 		}).Errorln(err)
+		hostResult.SetError(err)
+		return
 	}
 
 	log.WithFields(log.Fields{
@@ -199,35 +392,72 @@ func HostRekeyInit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
 	}).Debugln("Starting rekey init")
 
 	// check init status
-	sealed, init := vaultHelper.GetStatus(client)
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
 
 	if init == true && sealed == false {
 		// get the current leader to operate on
-		result, _ := client.Sys().Leader()
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return
+		}
 		// if we are the leader start the rekey
 		if result.IsSelf == true {
-			rekeyResult, err := client.Sys().RekeyInit(&api.RekeyInitRequest{SecretShares: shares, SecretThreshold: threshold})
+			rekeyResult, err := sysRekeyInit(client, &api.RekeyInitRequest{
+				SecretShares:        shares,
+				SecretThreshold:     threshold,
+				PGPKeys:             rekeyEncodedPGPKeys,
+				Backup:              rekeyBackup,
+				RequireVerification: requireVerification,
+			})
 			if err != nil {
 				log.Errorln("Rekey init error ", err)
+				hostResult.SetError(err)
+				return
 			}
 			if rekeyResult.Started {
 				log.WithFields(log.Fields{
-					"host":      vaultHelper.HostName,
-					"shares":    rekeyResult.N,
-					"threshold": rekeyResult.T,
-					"nonce":     rekeyResult.Nonce,
+					"host":                  vaultHelper.HostName,
+					"shares":                rekeyResult.N,
+					"threshold":             rekeyResult.T,
+					"nonce":                 rekeyResult.Nonce,
+					"verification_required": rekeyResult.VerificationRequired,
+					"backup":                rekeyResult.Backup,
 				}).Infoln("Rekey Started. Please supply your keys.")
+				hostResult.Message = "Rekey started"
+				hostResult.Threshold = rekeyResult.T
 			}
 		}
 	}
 }
 
-func HostRekeyStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
+func HostRekeyStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
 	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
 	client, err := vaultHelper.GetVaultClient()
 
 	if err != nil {
 		log.WithFields(log.Fields{"host": vaultHelper.HostName, "port": vaultHelper.Port}).Error(err)
+		hostResult.SetError(err)
+		return
 	}
 
 	log.WithFields(log.Fields{
@@ -235,13 +465,67 @@ func HostRekeyStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
 	}).Debugln("Starting rekey status")
 
 	// check init status
-	sealed, init := vaultHelper.GetStatus(client)
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
 
 	if init == true && sealed == false {
-		result, _ := client.Sys().Leader()
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return
+		}
 		// if we are the leader start the rekey
 		if result.IsSelf == true {
-			rekeyStatus, err := client.Sys().RekeyStatus()
+			// auto-unseal seals are rekeyed with recovery keys rather than unseal keys
+			sealStatus, err := client.Sys().SealStatus()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"host":  vaultHelper.HostName,
+					"port":  vaultHelper.Port,
+					"error": err,
+				}).Errorln("Error getting seal status")
+				hostResult.SetError(err)
+				return
+			}
+
+			keyType := "unseal"
+			if sealStatus.RecoverySeal {
+				keyType = "recovery"
+			}
+
+			log.WithFields(log.Fields{
+				"host":      vaultHelper.HostName,
+				"seal_type": sealStatus.Type,
+				"key_type":  keyType,
+			}).Infof("Vault is rekeyed with %s keys", keyType)
+
+			if sealStatus.RecoverySeal != recoveryKeys {
+				if sealStatus.RecoverySeal {
+					log.WithFields(log.Fields{
+						"host": vaultHelper.HostName,
+					}).Warnln("Vault uses an auto-unseal seal, pass --recovery to rekey its recovery keys")
+				} else {
+					log.WithFields(log.Fields{
+						"host": vaultHelper.HostName,
+					}).Warnln("Vault uses a shamir seal and has no recovery keys, drop --recovery")
+				}
+			}
+
+			rekeyStatus, err := sysRekeyStatus(client)
 
 			if err != nil {
 				log.WithFields(log.Fields{
@@ -249,27 +533,139 @@ func HostRekeyStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
 					"port":  vaultHelper.Port,
 					"error": err,
 				}).Errorln("Error getting rekey status")
+				hostResult.SetError(err)
+				return
 			}
 			if rekeyStatus.Started {
 				log.WithFields(log.Fields{
-					"host":      vaultHelper.HostName,
-					"shares":    rekeyStatus.N,
-					"threshold": rekeyStatus.T,
-					"nonce":     rekeyStatus.Nonce,
-					"progress":  rekeyStatus.Progress,
-					"required":  rekeyStatus.Required,
+					"host":                  vaultHelper.HostName,
+					"key_type":              keyType,
+					"shares":                rekeyStatus.N,
+					"threshold":             rekeyStatus.T,
+					"nonce":                 rekeyStatus.Nonce,
+					"progress":              rekeyStatus.Progress,
+					"required":              rekeyStatus.Required,
+					"verification_required": rekeyStatus.VerificationRequired,
 				}).Infoln("Rekey has been started")
+				hostResult.Message = "Rekey has been started"
+				hostResult.Progress = rekeyStatus.Progress
+				hostResult.Threshold = rekeyStatus.Required
+
+				// the verification nonce is only set once the new keys have been generated
+				if rekeyStatus.VerificationNonce != "" {
+					verificationStatus, err := sysRekeyVerificationStatus(client)
+					if err != nil {
+						log.WithFields(log.Fields{
+							"host":  vaultHelper.HostName,
+							"port":  vaultHelper.Port,
+							"error": err,
+						}).Errorln("Error getting rekey verification status")
+						hostResult.SetError(err)
+						return
+					}
+					log.WithFields(log.Fields{
+						"host":      vaultHelper.HostName,
+						"nonce":     verificationStatus.Nonce,
+						"progress":  verificationStatus.Progress,
+						"threshold": verificationStatus.T,
+					}).Infoln("Rekey awaiting verification of the new keys")
+					hostResult.Message = "Rekey awaiting verification"
+					hostResult.Progress = verificationStatus.Progress
+					hostResult.Threshold = verificationStatus.T
+				}
 			} else {
 				log.WithFields(log.Fields{
 					"host": vaultHelper.HostName,
 				}).Infoln("Rekey not started")
+				hostResult.Message = "Rekey not started"
 			}
 		}
 	}
 }
 
-func HostRekeySubmit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys []string) bool {
+func HostRekeyCancel(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
 	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
+	client, err := vaultHelper.GetVaultClient()
+
+	if err != nil {
+		log.WithFields(log.Fields{"host": vaultHelper.HostName, "port": vaultHelper.Port}).Error(err)
+		hostResult.SetError(err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting rekey cancel")
+
+	// check init status
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
+
+	if init == true && sealed == false {
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return
+		}
+		// only the leader knows about the rekey
+		if result.IsSelf == true {
+			dcLogger := log.WithFields(log.Fields{
+				"datacenter": vaultHelper.Datacenter,
+				"host":       vaultHelper.HostName,
+			})
+
+			rekeyStatus, err := sysRekeyStatus(client)
+			if err != nil {
+				dcLogger.WithFields(log.Fields{"error": err}).Errorln("Error getting rekey status")
+				hostResult.SetError(err)
+				return
+			}
+
+			if !rekeyStatus.Started {
+				dcLogger.Infoln("No rekey in progress")
+				hostResult.Message = "No rekey in progress"
+				return
+			}
+
+			if err := sysRekeyCancel(client); err != nil {
+				dcLogger.WithFields(log.Fields{"error": err}).Errorln("Error cancelling rekey")
+				hostResult.SetError(err)
+				return
+			}
+
+			dcLogger.WithFields(log.Fields{
+				"nonce":    rekeyStatus.Nonce,
+				"progress": rekeyStatus.Progress,
+			}).Infoln("Rekey cancelled")
+			hostResult.Message = "Rekey cancelled"
+		}
+	}
+}
+
+func HostRekeySubmit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys []string, results *ResultSet) bool {
+	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
 	client, err := vaultHelper.GetVaultClient()
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -277,6 +673,8 @@ func HostRekeySubmit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys [
 			"port":  vaultHelper.Port,
 			"error": err,
 		}).Errorln("Error getting vault client")
+		hostResult.SetError(err)
+		return false
 	}
 
 	log.WithFields(log.Fields{
@@ -284,74 +682,97 @@ func HostRekeySubmit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys [
 	}).Debugln("Starting rekey submit")
 
 	// check init status
-	sealed, init := vaultHelper.GetStatus(client)
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return false
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
 
 	if init == true && sealed == false {
-		result, _ := client.Sys().Leader()
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return false
+		}
 		// if we are the leader start the rekey
 		if result.IsSelf == true {
-			rekeyStatus, err := client.Sys().RekeyStatus()
+			rekeyStatus, err := sysRekeyStatus(client)
 			if err != nil {
 				log.WithFields(log.Fields{
 					"host":  vaultHelper.HostName,
 					"port":  vaultHelper.Port,
 					"error": err,
 				}).Errorln("Error getting rekey status")
-
+				hostResult.SetError(err)
 				return false
 			}
 
 			if rekeyStatus.Started {
+				// never submit to a rekey whose new keys would only be printed in plaintext
+				if len(rekeyStatus.PGPFingerprints) == 0 && !insecurePrintKeys {
+					log.WithFields(log.Fields{
+						"host": vaultHelper.HostName,
+					}).Errorln("Rekey was started without PGP keys, pass --insecure-print-keys to print the new keys in plaintext")
+					hostResult.Message = "Rekey was started without PGP keys"
+					return false
+				}
+
 				for _, vaultKey := range vaultKeys {
-					rekeyUpdate, err := client.Sys().RekeyUpdate(vaultKey, rekeyStatus.Nonce)
+					rekeyUpdate, err := sysRekeyUpdate(client, vaultKey, rekeyStatus.Nonce)
 					if err != nil {
 						log.WithFields(log.Fields{
 							"host":  vaultHelper.HostName,
 							"port":  vaultHelper.Port,
 							"error": err,
 						}).Errorln("Error updating rekey")
-
+						hostResult.SetError(err)
 						continue
 					}
 
 					if rekeyUpdate.Complete {
 
-						var outputKeys []string
-						var outputPgps []string
-
 						log.WithFields(log.Fields{
 							"host": vaultHelper.HostName,
 						}).Info("Rekey Complete")
+						hostResult.Message = "Rekey complete"
 
-						for _, key := range rekeyUpdate.KeysB64 {
-							outputKeys = append(outputKeys, key)
-						}
-
-						for _, pgp := range rekeyUpdate.PGPFingerprints {
-							outputPgps = append(outputPgps, pgp)
-						}
-
-						for _, outputPgp := range outputPgps {
+						if err := storeRekeyKeys(vaultHelper.Datacenter, rekeyUpdate); err != nil {
 							log.WithFields(log.Fields{
-								"PGP Fingerprint": outputPgp,
-							}).Infoln("New Key Generated")
+								"host":  vaultHelper.HostName,
+								"error": err,
+							}).Errorln("Error storing new keys")
+							hostResult.SetError(err)
 						}
 
-						for _, outputKey := range outputKeys {
+						if rekeyUpdate.VerificationRequired {
 							log.WithFields(log.Fields{
-								"Key": outputKey,
-							}).Infoln("New Key Generated")
+								"host":               vaultHelper.HostName,
+								"verification_nonce": rekeyUpdate.VerificationNonce,
+							}).Infoln("The old keys remain valid until the new keys are verified with: hookpick rekey verify")
 						}
 
 						break
 					} else {
-						newRekeyStatus, err := client.Sys().RekeyStatus()
+						newRekeyStatus, err := sysRekeyStatus(client)
 						if err != nil {
 							log.WithFields(log.Fields{
 								"host":  vaultHelper.HostName,
 								"port":  vaultHelper.Port,
 								"error": err,
 							}).Errorln("Error getting rekey status")
+							hostResult.SetError(err)
+							continue
 						}
 						log.WithFields(log.Fields{
 							"host":      vaultHelper.HostName,
@@ -361,25 +782,441 @@ func HostRekeySubmit(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys [
 							"progress":  newRekeyStatus.Progress,
 							"required":  newRekeyStatus.Required,
 						}).Infoln("Key submitted")
+						hostResult.Message = "Key submitted"
+						hostResult.Progress = newRekeyStatus.Progress
+						hostResult.Threshold = newRekeyStatus.Required
 					}
 				}
 			} else {
 				log.WithFields(log.Fields{
 					"host": vaultHelper.HostName,
 				}).Infoln("Rekey not started")
+				hostResult.Message = "Rekey not started"
+			}
+		}
+	}
+	return true
+}
+
+// GetNewVaultKeys reads the new keys for a datacenter from --key-file. Files
+// named <datacenter>-<fingerprint>.key by rekey submit are only used for
+// their datacenter, any other file is used for every datacenter. Keys are
+// PGP encrypted and decrypted with gpg, unless --plaintext-key-files is set.
+func GetNewVaultKeys(dc config.Datacenter, _ ConfigKeyGetter, keyDecrypter gpg.StringDecrypter) []string {
+	var vaultKeys []string
+	for _, path := range rekeyKeyFiles {
+		if keyDC := keyFileDatacenter(path); keyDC != "" && keyDC != dc.Name {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatal("Error reading key file: ", err)
+		}
+		key := strings.TrimSpace(string(contents))
+
+		if !rekeyPlaintextKeyFiles {
+			key, err = keyDecrypter(key)
+			if err != nil {
+				log.Fatal("GPG Decryption Error: ", err)
+			}
+		}
+
+		vaultKeys = append(vaultKeys, key)
+	}
+
+	return vaultKeys
+}
+
+// keyFileDatacenter returns the datacenter a key file written by
+// storeRekeyKeys belongs to, or nothing if it isn't named like one
+func keyFileDatacenter(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".key")
+	if name == filepath.Base(path) {
+		return ""
+	}
+
+	i := strings.LastIndex(name, "-")
+	if i <= 0 {
+		return ""
+	}
+
+	fingerprint := name[i+1:]
+	if len(fingerprint) < 8 || strings.Trim(strings.ToLower(fingerprint), "0123456789abcdef") != "" {
+		return ""
+	}
+
+	return name[:i]
+}
+
+// storeRekeyKeys writes each PGP encrypted key to its own file, named after
+// the fingerprint of the key it was encrypted with. Plaintext keys are only
+// ever printed, and only when --insecure-print-keys is set.
+func storeRekeyKeys(dcName string, rekeyUpdate *api.RekeyUpdateResponse) error {
+
+	if len(rekeyUpdate.PGPFingerprints) == 0 {
+		if !insecurePrintKeys {
+			return errors.New("new keys are not PGP encrypted and --insecure-print-keys is not set")
+		}
+		for _, key := range rekeyUpdate.KeysB64 {
+			log.WithFields(log.Fields{
+				"datacenter": dcName,
+				"Key":        key,
+			}).Infoln("New Key Generated")
+		}
+		return nil
+	}
+
+	if len(rekeyUpdate.PGPFingerprints) != len(rekeyUpdate.KeysB64) {
+		return fmt.Errorf("received %d keys for %d PGP fingerprints", len(rekeyUpdate.KeysB64), len(rekeyUpdate.PGPFingerprints))
+	}
+
+	if err := os.MkdirAll(rekeyOutputDir, 0700); err != nil {
+		return err
+	}
+
+	for i, fingerprint := range rekeyUpdate.PGPFingerprints {
+		path := filepath.Join(rekeyOutputDir, fmt.Sprintf("%s-%s.key", dcName, fingerprint))
+		if err := ioutil.WriteFile(path, []byte(rekeyUpdate.KeysB64[i]+"\n"), 0600); err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{
+			"datacenter":      dcName,
+			"PGP Fingerprint": fingerprint,
+			"path":            path,
+		}).Infoln("New Key Generated")
+	}
+
+	return nil
+}
+
+func HostRekeyBackupRetrieve(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
+	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
+	client, err := vaultHelper.GetVaultClient()
+
+	if err != nil {
+		log.WithFields(log.Fields{"host": vaultHelper.HostName, "port": vaultHelper.Port}).Error(err)
+		hostResult.SetError(err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting rekey backup retrieve")
+
+	// check init status
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
+
+	if init == true && sealed == false {
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return
+		}
+		if result.IsSelf == true {
+			dcLogger := log.WithFields(log.Fields{
+				"datacenter": vaultHelper.Datacenter,
+				"host":       vaultHelper.HostName,
+			})
+
+			var backup *api.RekeyRetrieveResponse
+			prefix := "backup"
+			if recoveryKeys {
+				backup, err = client.Sys().RekeyRetrieveRecoveryBackup()
+				prefix = "recovery-backup"
+			} else {
+				backup, err = client.Sys().RekeyRetrieveBackup()
+			}
+			if err != nil {
+				dcLogger.WithFields(log.Fields{"error": err}).Errorln("Error retrieving rekey backup")
+				hostResult.SetError(err)
+				return
+			}
+
+			if err := os.MkdirAll(rekeyOutputDir, 0700); err != nil {
+				dcLogger.WithFields(log.Fields{"error": err}).Errorln("Error creating output directory")
+				hostResult.SetError(err)
+				return
+			}
+
+			for fingerprint, keys := range backup.KeysB64 {
+				path := filepath.Join(rekeyOutputDir, fmt.Sprintf("%s-%s-%s.key", vaultHelper.Datacenter, prefix, fingerprint))
+				if err := ioutil.WriteFile(path, []byte(strings.Join(keys, "\n")+"\n"), 0600); err != nil {
+					dcLogger.WithFields(log.Fields{"error": err}).Errorln("Error writing backed up keys")
+					hostResult.SetError(err)
+					continue
+				}
+				dcLogger.WithFields(log.Fields{
+					"nonce":           backup.Nonce,
+					"PGP Fingerprint": fingerprint,
+					"keys":            len(keys),
+					"path":            path,
+				}).Infoln("Backed up keys retrieved")
+				hostResult.Message = "Backed up keys retrieved"
+			}
+		}
+	}
+}
+
+func HostRekeyBackupDelete(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
+	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
+	client, err := vaultHelper.GetVaultClient()
+
+	if err != nil {
+		log.WithFields(log.Fields{"host": vaultHelper.HostName, "port": vaultHelper.Port}).Error(err)
+		hostResult.SetError(err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting rekey backup delete")
+
+	// check init status
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
+
+	if init == true && sealed == false {
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return
+		}
+		if result.IsSelf == true {
+			dcLogger := log.WithFields(log.Fields{
+				"datacenter": vaultHelper.Datacenter,
+				"host":       vaultHelper.HostName,
+			})
+
+			if recoveryKeys {
+				err = client.Sys().RekeyDeleteRecoveryBackup()
+			} else {
+				err = client.Sys().RekeyDeleteBackup()
+			}
+			if err != nil {
+				dcLogger.WithFields(log.Fields{"error": err}).Errorln("Error deleting rekey backup")
+				hostResult.SetError(err)
+				return
+			}
+
+			dcLogger.Infoln("Rekey backup deleted")
+			hostResult.Message = "Rekey backup deleted"
+		}
+	}
+}
+
+func HostRekeyVerify(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys []string, results *ResultSet) bool {
+	defer wg.Done()
+
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.AddIfReported(hostResult) }()
+
+	client, err := vaultHelper.GetVaultClient()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"port":  vaultHelper.Port,
+			"error": err,
+		}).Errorln("Error getting vault client")
+		hostResult.SetError(err)
+		return false
+	}
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting rekey verify")
+
+	// check init status
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return false
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
+
+	if init == true && sealed == false {
+		result, err := client.Sys().Leader()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting leader")
+			hostResult.SetError(err)
+			return false
+		}
+		if result.IsSelf == true {
+			verificationStatus, err := sysRekeyVerificationStatus(client)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"host":  vaultHelper.HostName,
+					"port":  vaultHelper.Port,
+					"error": err,
+				}).Errorln("Error getting rekey verification status")
+				hostResult.SetError(err)
+				return false
+			}
+
+			if !verificationStatus.Started {
+				log.WithFields(log.Fields{
+					"host": vaultHelper.HostName,
+				}).Infoln("Rekey verification not started")
+				hostResult.Message = "Rekey verification not started"
+				return true
+			}
+
+			for _, vaultKey := range vaultKeys {
+				verifyUpdate, err := sysRekeyVerificationUpdate(client, vaultKey, verificationStatus.Nonce)
+				if err != nil {
+					log.WithFields(log.Fields{
+						"host":  vaultHelper.HostName,
+						"port":  vaultHelper.Port,
+						"error": err,
+					}).Errorln("Error verifying rekey")
+					hostResult.SetError(err)
+					continue
+				}
+
+				if verifyUpdate.Complete {
+					log.WithFields(log.Fields{
+						"host": vaultHelper.HostName,
+					}).Infoln("Rekey verified. The new keys are now active")
+					hostResult.Message = "Rekey verified"
+					break
+				}
+
+				newVerificationStatus, err := sysRekeyVerificationStatus(client)
+				if err != nil {
+					log.WithFields(log.Fields{
+						"host":  vaultHelper.HostName,
+						"port":  vaultHelper.Port,
+						"error": err,
+					}).Errorln("Error getting rekey verification status")
+					continue
+				}
+				log.WithFields(log.Fields{
+					"host":      vaultHelper.HostName,
+					"nonce":     newVerificationStatus.Nonce,
+					"progress":  newVerificationStatus.Progress,
+					"threshold": newVerificationStatus.T,
+				}).Infoln("New key submitted")
+				hostResult.Message = "New key submitted"
+				hostResult.Progress = newVerificationStatus.Progress
+				hostResult.Threshold = newVerificationStatus.T
 			}
 		}
 	}
 	return true
 }
 
+// the sys* helpers pick the recovery key endpoints over the
+// unseal key endpoints when --recovery is set
+
+func sysRekeyInit(client *api.Client, config *api.RekeyInitRequest) (*api.RekeyStatusResponse, error) {
+	if recoveryKeys {
+		return client.Sys().RekeyRecoveryKeyInit(config)
+	}
+	return client.Sys().RekeyInit(config)
+}
+
+func sysRekeyStatus(client *api.Client) (*api.RekeyStatusResponse, error) {
+	if recoveryKeys {
+		return client.Sys().RekeyRecoveryKeyStatus()
+	}
+	return client.Sys().RekeyStatus()
+}
+
+func sysRekeyUpdate(client *api.Client, shard, nonce string) (*api.RekeyUpdateResponse, error) {
+	if recoveryKeys {
+		return client.Sys().RekeyRecoveryKeyUpdate(shard, nonce)
+	}
+	return client.Sys().RekeyUpdate(shard, nonce)
+}
+
+func sysRekeyCancel(client *api.Client) error {
+	if recoveryKeys {
+		return client.Sys().RekeyRecoveryKeyCancel()
+	}
+	return client.Sys().RekeyCancel()
+}
+
+func sysRekeyVerificationStatus(client *api.Client) (*api.RekeyVerificationStatusResponse, error) {
+	if recoveryKeys {
+		return client.Sys().RekeyRecoveryKeyVerificationStatus()
+	}
+	return client.Sys().RekeyVerificationStatus()
+}
+
+func sysRekeyVerificationUpdate(client *api.Client, shard, nonce string) (*api.RekeyVerificationUpdateResponse, error) {
+	if recoveryKeys {
+		return client.Sys().RekeyRecoveryKeyVerificationUpdate(shard, nonce)
+	}
+	return client.Sys().RekeyVerificationUpdate(shard, nonce)
+}
+
 func init() {
 	RootCmd.AddCommand(rekeyCmd)
-	rekeyCmd.AddCommand(initCmd)
-	rekeyCmd.AddCommand(submitCmd)
+	rekeyCmd.AddCommand(rekeyInitCmd)
+	rekeyCmd.AddCommand(rekeySubmitCmd)
 	rekeyCmd.AddCommand(rekeyStatusCmd)
+	rekeyCmd.AddCommand(rekeyCancelCmd)
+	rekeyCmd.AddCommand(rekeyVerifyCmd)
+	rekeyCmd.AddCommand(rekeyBackupCmd)
+	rekeyBackupCmd.AddCommand(rekeyBackupRetrieveCmd)
+	rekeyBackupCmd.AddCommand(rekeyBackupDeleteCmd)
 
-	initCmd.Flags().IntVarP(&shares, "shares", "s", 0, "The number of secret shares to init the rekey with")
-	initCmd.Flags().IntVarP(&threshold, "threshold", "t", 0, "The secret threshold to init the rekey with")
+	rekeyInitCmd.Flags().IntVarP(&shares, "shares", "s", 0, "The number of secret shares to init the rekey with")
+	rekeyInitCmd.Flags().IntVarP(&threshold, "threshold", "t", 0, "The secret threshold to init the rekey with")
+	rekeyCmd.PersistentFlags().BoolVar(&insecurePrintKeys, "insecure-print-keys", false, "Print the new keys in plaintext when the rekey is not using PGP keys")
+	rekeyInitCmd.Flags().StringSliceVar(&rekeyPGPKeys, "pgp-keys", nil, "Comma separated list of PGP public key files to encrypt the new keys with, one per share")
+	rekeySubmitCmd.Flags().StringVar(&rekeyOutputDir, "output-dir", ".", "Directory to write the PGP encrypted new keys to, one file per fingerprint")
+	rekeyInitCmd.Flags().BoolVar(&rekeyBackup, "backup", false, "Store a PGP encrypted backup of the new keys in Vault")
+	rekeyCmd.PersistentFlags().BoolVar(&recoveryKeys, "recovery", false, "Rekey the recovery keys of an auto-unseal Vault instead of the unseal keys")
+	rekeyBackupRetrieveCmd.Flags().StringVar(&rekeyOutputDir, "output-dir", ".", "Directory to write the backed up keys to, one file per fingerprint")
+	rekeyVerifyCmd.Flags().StringSliceVar(&rekeyKeyFiles, "key-file", nil, "File holding a new key to verify, like those written by rekey submit. Can be repeated")
+	rekeyVerifyCmd.Flags().BoolVar(&rekeyPlaintextKeyFiles, "plaintext-key-files", false, "The key files hold plaintext keys, from a rekey without PGP keys, rather than PGP encrypted ones")
+	rekeyInitCmd.Flags().BoolVar(&requireVerification, "require-verification", false, "Require the new keys to be verified with rekey verify before they replace the old ones")
 
 }
@@ -21,8 +21,11 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -34,10 +37,21 @@ import (
 )
 
 var (
-	cfgFile     string
-	datacenter  string
-	datacenters []config.Datacenter
-	debug       bool
+	cfgFile    string
+	datacenter []string
+	// excludeDatacenters : datacenters to leave out, by name or glob
+	excludeDatacenters []string
+	datacenters        []config.Datacenter
+	debug              bool
+	token              string
+	tokenFile          string
+	// selectorFlag and hostFlags narrow down the hosts commands operate on
+	selectorFlag string
+	hostFlags    []string
+	// configErr : the error reading the config file, if there was one
+	configErr error
+	// outputFormat : the format per-host results are printed to stdout in
+	outputFormat string
 	// Version : This is for the Version command
 	Version string
 )
@@ -48,14 +62,25 @@ var RootCmd = &cobra.Command{
 	Short: "A tool to manage Vault clusters",
 	Long: `Easily unseal, rekey and init multiple Vault servers in a large,
 distributed environment`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if cmd.Annotations[annotationMutating] == "true" {
+			validateConfig()
+		}
+	},
 }
 
+// annotationMutating marks commands that change vault. The config file is
+// validated before they run.
+const annotationMutating = "hookpick.mutating"
+
+var mutating = map[string]string{annotationMutating: "true"}
+
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute(version string) {
 	Version = version
 	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(-1)
 	}
 }
@@ -64,8 +89,14 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.hookpick.yaml)")
-	RootCmd.PersistentFlags().StringVarP(&datacenter, "datacenter", "d", "", "datacenter to operate on")
+	RootCmd.PersistentFlags().StringSliceVarP(&datacenter, "datacenter", "d", nil, "datacenter to operate on, by name or glob like 'eu-*'. Can be repeated")
+	RootCmd.PersistentFlags().StringSliceVar(&excludeDatacenters, "exclude-datacenter", nil, "datacenter to leave out, by name or glob. Can be repeated")
+	RootCmd.PersistentFlags().StringVar(&selectorFlag, "selector", "", "only operate on hosts whose tags and labels match, e.g. role=voter,zone=a")
+	RootCmd.PersistentFlags().StringSliceVar(&hostFlags, "host", nil, "only operate on this host, by name, name:port or address. Can be repeated")
 	RootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug logging")
+	RootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "print per-host results to stdout as json, yaml or table")
+	RootCmd.PersistentFlags().StringVar(&token, "token", "", "Vault token for operations that require one (default is the --token-file contents, then $VAULT_TOKEN)")
+	RootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "file to read the Vault token from, used instead of $VAULT_TOKEN")
 	viper.BindPFlag("datacenter", RootCmd.PersistentFlags().Lookup("datacenter"))
 
 	if os.Getenv("VAULT_ADDR") != "" {
@@ -75,41 +106,153 @@ func init() {
 
 type ConfigStringGetter func() string
 type ConfigKeyGetter func(string, g.StringDecrypter) (bool, string)
+type ConfigTLSGetter func(config.Datacenter, config.Host) config.TLS
 
 type ConfigHelper struct {
-	GetDC        ConfigStringGetter
-	GetCAPath    ConfigStringGetter
-	GetURLScheme ConfigStringGetter
-	GetGPGKey    ConfigKeyGetter
+	GetCAPath ConfigStringGetter
+	GetGPGKey ConfigKeyGetter
+	GetTLS    ConfigTLSGetter
 }
 
-func NewConfigHelper(dcgetter, cagetter, schemegetter ConfigStringGetter, gpgkeygetter ConfigKeyGetter) *ConfigHelper {
+func NewConfigHelper(cagetter ConfigStringGetter, gpgkeygetter ConfigKeyGetter, tlsgetter ConfigTLSGetter) *ConfigHelper {
 	return &ConfigHelper{
-		GetDC:        dcgetter,
-		GetCAPath:    cagetter,
-		GetURLScheme: schemegetter,
-		GetGPGKey:    gpgkeygetter,
+		GetCAPath: cagetter,
+		GetGPGKey: gpgkeygetter,
+		GetTLS:    tlsgetter,
 	}
 }
 
 func GetDatacenters() []config.Datacenter {
 
-	err := viper.UnmarshalKey("datacenters", &datacenters)
-
+	datacenters, err := loadDatacenters()
 	if err != nil {
-		log.Errorf("Unable to read hosts key in config file: %s", err)
+		log.Fatal(err)
 	}
 
 	return datacenters
 
 }
 
+// loadDatacenters reads the datacenters from the config file and resolves
+// their hosts
+func loadDatacenters() ([]config.Datacenter, error) {
+
+	if configErr != nil {
+		return nil, fmt.Errorf("Unable to read config file: %s", configErr)
+	}
+
+	if err := viper.UnmarshalKey("datacenters", &datacenters); err != nil {
+		return nil, fmt.Errorf("Unable to read hosts key in config file: %s", err)
+	}
+
+	for i := range datacenters {
+		if err := datacenters[i].Resolve(GetProtocol()); err != nil {
+			return nil, fmt.Errorf("Invalid host in config file: %s", err)
+		}
+	}
+
+	return datacenters, nil
+
+}
+
+// GetTargets returns the datacenters and hosts selected by --datacenter,
+// --exclude-datacenter, --host and --selector. Every command operates on these.
+func GetTargets() []config.Datacenter {
+
+	targets, err := selectTargets()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(targets) == 0 {
+		log.Warnln("No hosts selected")
+	}
+
+	return targets
+
+}
+
+// selectTargets applies the targeting flags to the datacenters in the config file
+func selectTargets() ([]config.Datacenter, error) {
+
+	selector, err := config.ParseSelector(selectorFlag)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid selector: %s", err)
+	}
+
+	target := config.Target{
+		Datacenters:        GetSpecificDatacenters(),
+		ExcludeDatacenters: excludeDatacenters,
+		Hosts:              hostFlags,
+		Selector:           selector,
+	}
+
+	allDCs, err := loadDatacenters()
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := target.Filter(allDCs)
+	if err != nil {
+		return nil, fmt.Errorf("Error selecting hosts: %s", err)
+	}
+
+	return targets, nil
+
+}
+
+// GetTargetDatacenters returns every host in each datacenter that has a host
+// selected. Operations that act on a whole cluster, like rekeying, use this
+// so that narrowing down the hosts can't leave out the active node.
+func GetTargetDatacenters() []config.Datacenter {
+
+	selected := make(map[string]bool)
+	for _, dc := range GetTargets() {
+		selected[dc.Name] = true
+	}
+
+	var targets []config.Datacenter
+	for _, dc := range GetDatacenters() {
+		if selected[dc.Name] {
+			targets = append(targets, dc)
+		}
+	}
+
+	return targets
+
+}
+
 func GetCaPath() string {
 
 	return viper.GetString("capath")
 
 }
 
+// GetTLSConfig returns the TLS settings for a host. Host settings override
+// datacenter settings, which override the global settings.
+func GetTLSConfig(dc config.Datacenter, host config.Host) config.TLS {
+
+	global := config.TLS{
+		CACert:        viper.GetString("ca_cert"),
+		CAPath:        viper.GetString("ca_path"),
+		ClientCert:    viper.GetString("client_cert"),
+		ClientKey:     viper.GetString("client_key"),
+		TLSServerName: viper.GetString("tls_server_name"),
+	}
+
+	if global.CACert == "" && global.CAPath == "" {
+		global.CAPath = GetCaPath()
+	}
+
+	if viper.IsSet("tls_skip_verify") {
+		skipVerify := viper.GetBool("tls_skip_verify")
+		global.TLSSkipVerify = &skipVerify
+	}
+
+	return global.Merge(dc.TLS).Merge(host.TLS)
+
+}
+
 func GetGpgKey(key string, keyDecrypt g.StringDecrypter) (bool, string) {
 
 	gpg := viper.GetBool("gpg")
@@ -130,9 +273,9 @@ func GetGpgKey(key string, keyDecrypt g.StringDecrypter) (bool, string) {
 
 }
 
-func GetSpecificDatacenter() string {
+func GetSpecificDatacenters() []string {
 
-	return viper.GetString("datacenter")
+	return viper.GetStringSlice("datacenter")
 
 }
 
@@ -144,6 +287,41 @@ func GetProtocol() string {
 
 }
 
+// GetToken returns the Vault token from the flag, token file or environment, in that order
+func GetToken() (string, error) {
+
+	if token != "" {
+		return token, nil
+	}
+
+	if tokenFile != "" {
+		contents, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(contents)), nil
+	}
+
+	return os.Getenv("VAULT_TOKEN"), nil
+
+}
+
+// confirm asks the operator a yes/no question on stdin
+func confirm(prompt string) bool {
+
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" { // enable ability to specify config file via flag
@@ -157,9 +335,18 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println("Error reading config file: ", err)
+		configErr = err
+		fmt.Fprintln(os.Stderr, "Error reading config file: ", err)
 	}
+
+	// keep stdout for --output
+	log.SetOutput(os.Stderr)
+
 	if debug {
 		log.SetLevel(log.DebugLevel)
 	}
+
+	if !validOutputFormat(outputFormat) {
+		log.Fatalf("Unknown output format %q: must be one of json, yaml or table", outputFormat)
+	}
 }
@@ -0,0 +1,197 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/jaxxstorm/hookpick/config"
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+var sealYes bool
+
+// sealCmd represents the seal command
+var sealCmd = &cobra.Command{
+	Use:         "seal",
+	Annotations: mutating,
+	Short:       "Seal the vaults in an emergency",
+	Long: `Sends a seal operation to all vaults in the configuration file,
+or the specified datacenter, in parallel. This requires a token
+which is allowed to seal Vault`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		vaultToken, err := GetToken()
+		if err != nil {
+			log.Fatal("Error reading token: ", err)
+		}
+
+		if vaultToken == "" {
+			log.Fatal("Sealing Vault requires a token: See --help")
+		}
+
+		allDCs := GetTargets()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+		var dcNames []string
+		hostCount := 0
+		for _, dc := range allDCs {
+			dcNames = append(dcNames, dc.Name)
+			hostCount += len(dc.Hosts)
+		}
+
+		if !sealYes && !confirm(fmt.Sprintf("This will seal %d hosts in datacenters %v. Continue?", hostCount, dcNames)) {
+			log.Fatal("Seal aborted")
+		}
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			log.WithFields(log.Fields{
+				"datacenter": dc.Name,
+			}).Debugln("Starting to process Vault seal")
+
+			go ProcessSeal(&wg, dc, configHelper, v.NewVaultHelper, vaultToken, SealHost, results)
+		}
+		wg.Wait()
+
+		if outputFormat == "" {
+			logSealResults(results)
+		}
+		renderResults(results)
+		exitWithResults(results, false)
+	},
+}
+
+// logSealResults reports which hosts confirmed they are sealed
+func logSealResults(results *ResultSet) {
+	for _, result := range results.Sorted() {
+		hostLogger := log.WithFields(log.Fields{
+			"datacenter": result.Datacenter,
+			"host":       result.Host,
+		})
+
+		if result.Error != "" {
+			hostLogger.WithFields(log.Fields{"error": result.Error}).Errorln("Vault could not be confirmed sealed")
+		} else if result.Sealed {
+			hostLogger.Infoln("Vault confirmed sealed")
+		} else {
+			hostLogger.Errorln("Vault is still unsealed")
+		}
+	}
+}
+
+type HostSealImpl func(*sync.WaitGroup, *v.VaultHelper, *ResultSet)
+
+func ProcessSeal(wg *sync.WaitGroup,
+	dc config.Datacenter,
+	configHelper *ConfigHelper,
+	vhGetter v.VaultHelperGetter,
+	vaultToken string,
+	sealHost HostSealImpl,
+	results *ResultSet) {
+
+	defer wg.Done()
+
+	caPath := configHelper.GetCAPath()
+
+	log.WithFields(log.Fields{
+		"datacenter": dc.Name,
+	}).Debugln("Processing Datacenter")
+
+	hwg := sync.WaitGroup{}
+	for _, host := range dc.Hosts {
+		hwg.Add(1)
+		log.WithFields(log.Fields{
+			"host": host.Name,
+		}).Debugln("Processing host")
+
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		vaultHelper.Token = vaultToken
+		go sealHost(&hwg, vaultHelper, results)
+	}
+	hwg.Wait()
+}
+
+func SealHost(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
+	defer wg.Done()
+
+	result := NewHostResult(vaultHelper)
+	defer func() { results.Add(result) }()
+
+	log.WithFields(log.Fields{
+		"host": vaultHelper.HostName,
+	}).Debugln("Starting seal")
+
+	client, err := vaultHelper.GetVaultClient()
+	if err != nil {
+		result.SetUnreachable(err)
+		return
+	}
+
+	sealStatus, err := client.Sys().SealStatus()
+	if err != nil {
+		result.SetUnreachable(err)
+		return
+	}
+
+	// sealing an already sealed vault is an error, so skip it
+	if !sealStatus.Sealed {
+		if err := client.Sys().Seal(); err != nil {
+			result.SetError(err)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"host": vaultHelper.HostName,
+		}).Infoln("Seal operation performed")
+	}
+
+	// confirm the seal took effect
+	sealStatus, err = client.Sys().SealStatus()
+	if err != nil {
+		result.SetError(err)
+		return
+	}
+
+	result.Sealed = sealStatus.Sealed
+	result.Initialized = sealStatus.Initialized
+	if result.Sealed {
+		result.Message = "Vault confirmed sealed"
+	} else {
+		result.Error = "Vault is still unsealed"
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(sealCmd)
+
+	sealCmd.Flags().BoolVarP(&sealYes, "yes", "y", false, "Seal without asking for confirmation")
+
+}
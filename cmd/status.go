@@ -27,10 +27,15 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"sync"
+	"time"
 
 	"github.com/jaxxstorm/hookpick/config"
 )
 
+var statusWatch bool
+var statusInterval time.Duration
+var statusConsistency bool
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
@@ -39,63 +44,99 @@ var statusCmd = &cobra.Command{
 specified in the configuration file`,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		datacenters := GetDatacenters()
-		configHelper := NewConfigHelper(GetSpecificDatacenter, GetCaPath, GetProtocol, GetGpgKey)
-		wg := sync.WaitGroup{}
-
-		for _, dc := range datacenters {
-			wg.Add(1)
-			log.WithFields(log.Fields{
-				"datacenter": dc.Name,
-			}).Debugln("Starting to process")
-			go ProcessStatus(&wg, dc, configHelper, v.NewVaultHelper, GetHostStatus)
+		if statusWatch {
+			watchStatus(statusInterval)
+			return
 		}
-		wg.Wait()
+
+		results := collectStatus()
+
+		renderResults(results)
+		exitWithResults(results, true)
 	},
 }
 
-type HostImpl func(*sync.WaitGroup, *v.VaultHelper)
+// collectStatus fetches the status of every host
+func collectStatus() *ResultSet {
+	return collectStatusOf(GetTargets())
+}
+
+// collectStatusOf fetches the status of every host in the datacenters
+func collectStatusOf(datacenters []config.Datacenter) *ResultSet {
+
+	configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+	wg := sync.WaitGroup{}
+	results := &ResultSet{}
+
+	for _, dc := range datacenters {
+		wg.Add(1)
+		log.WithFields(log.Fields{
+			"datacenter": dc.Name,
+		}).Debugln("Starting to process")
+		go ProcessStatus(&wg, dc, configHelper, v.NewVaultHelper, GetHostStatus, results)
+	}
+	wg.Wait()
+
+	if statusConsistency {
+		checkConsistency(results)
+	}
+
+	return results
+}
+
+// quietHostStatus logs the status of each host at debug level, for commands
+// like the exporter that fetch it over and over
+var quietHostStatus bool
+
+func hostStatusLevel(level log.Level) log.Level {
+	if quietHostStatus {
+		return log.DebugLevel
+	}
+	return level
+}
+
+type HostImpl func(*sync.WaitGroup, *v.VaultHelper, *ResultSet)
 
 func ProcessStatus(wg *sync.WaitGroup,
 	dc config.Datacenter,
 	configHelper *ConfigHelper,
 	vhGetter v.VaultHelperGetter,
-	hostStatusGetter HostImpl) {
+	hostStatusGetter HostImpl,
+	results *ResultSet) {
 
 	defer wg.Done()
 
-	specificDC := configHelper.GetDC()
 	caPath := configHelper.GetCAPath()
-	protocol := configHelper.GetURLScheme()
 
 	log.WithFields(log.Fields{
 		"datacenter": dc.Name,
-		"dc":         specificDC,
 	}).Debugln("Processing Datacenter")
 
-	if specificDC == dc.Name || specificDC == "" {
+	hwg := sync.WaitGroup{}
+	for _, host := range dc.Hosts {
+		hwg.Add(1)
 
-		hwg := sync.WaitGroup{}
-		for _, host := range dc.Hosts {
-			hwg.Add(1)
-
-			log.WithFields(log.Fields{
-				"host": host.Name,
-			}).Debugln("Processing Host")
+		log.WithFields(log.Fields{
+			"host": host.Name,
+		}).Debugln("Processing Host")
 
-			vaultHelper := vhGetter(host.Name, caPath, protocol, host.Port, v.Status)
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
 
-			go hostStatusGetter(&hwg, vaultHelper)
-		}
-		hwg.Wait()
+		go hostStatusGetter(&hwg, vaultHelper, results)
 	}
+	hwg.Wait()
 }
 
-func GetHostStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
+func GetHostStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, results *ResultSet) {
 	// set hostnames for waitgroup
 
 	defer wg.Done()
 
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.Add(hostResult) }()
+
 	log.WithFields(log.Fields{
 		"host": vaultHelper.HostName,
 	}).Debugln("Starting status fetch")
@@ -106,31 +147,74 @@ func GetHostStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
 		log.WithFields(log.Fields{
 			"host":  vaultHelper.HostName,
 			"error": err,
-		}).Errorln("Error creating vault client")
+		}).Log(hostStatusLevel(log.ErrorLevel), "Error creating vault client")
+		hostResult.SetUnreachable(err)
+		return
 	}
 
-	// get the seal status
+	// reachability comes from sys/health, like every other command
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Log(hostStatusLevel(log.ErrorLevel), "Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return
+	}
+	hostResult.Sealed = state.Sealed()
+	hostResult.Initialized = state.Initialized()
+
+	// the seal status has the unseal progress and cluster details
 	result, err := client.Sys().SealStatus()
 
 	if err != nil {
 		log.WithFields(log.Fields{
 			"host":  vaultHelper.HostName,
 			"error": err,
-		}).Errorln("Error getting seal status")
+		}).Log(hostStatusLevel(log.ErrorLevel), "Error getting seal status")
+		hostResult.SetError(err)
 	} else {
+		hostResult.Progress = result.Progress
+		hostResult.Threshold = result.T
+		hostResult.Migration = result.Migration
+
+		// node details are best effort, the seal status is what matters
+		nodeInfo, err := v.NodeDetails(client, result)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Log(hostStatusLevel(log.WarnLevel), "Error getting node details")
+		}
+		hostResult.SetNodeInfo(nodeInfo)
+
 		// only check the seal status if we have a client
-		if result.Sealed == true {
+		if state.Sealed() {
 			log.WithFields(log.Fields{
 				"host":      vaultHelper.HostName,
 				"progress":  result.Progress,
 				"threshold": result.T,
-			}).Errorln("Vault is sealed!")
+				"migration": result.Migration,
+				"version":   nodeInfo.Version,
+				"seal_type": nodeInfo.SealType,
+			}).Log(hostStatusLevel(log.ErrorLevel), "Vault is sealed!")
+			hostResult.Message = "Vault is sealed"
 		} else {
 			log.WithFields(log.Fields{
-				"host":      vaultHelper.HostName,
-				"progress":  result.Progress,
-				"threshold": result.T,
-			}).Infoln("Vault is unsealed!")
+				"host":                 vaultHelper.HostName,
+				"progress":             result.Progress,
+				"threshold":            result.T,
+				"version":              nodeInfo.Version,
+				"cluster_name":         nodeInfo.ClusterName,
+				"cluster_id":           nodeInfo.ClusterID,
+				"seal_type":            nodeInfo.SealType,
+				"ha_enabled":           nodeInfo.HAEnabled,
+				"role":                 nodeInfo.Role,
+				"leader":               nodeInfo.LeaderAddress,
+				"raft_committed_index": nodeInfo.RaftCommittedIndex,
+			}).Log(hostStatusLevel(log.InfoLevel), "Vault is unsealed!")
+			hostResult.Message = "Vault is unsealed"
 		}
 	}
 }
@@ -138,6 +222,10 @@ func GetHostStatus(wg *sync.WaitGroup, vaultHelper *v.VaultHelper) {
 func init() {
 	RootCmd.AddCommand(statusCmd)
 
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Keep polling every host and redraw the status table until interrupted")
+	statusCmd.Flags().BoolVar(&statusConsistency, "consistency", false, "Flag hosts whose version, cluster ID, leadership or threshold disagree with the rest of their datacenter")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 5*time.Second, "How often to poll every host when using --watch")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
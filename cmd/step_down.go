@@ -0,0 +1,320 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/jaxxstorm/hookpick/config"
+	v "github.com/jaxxstorm/hookpick/vault"
+)
+
+var stepDownPrefer string
+var stepDownWait time.Duration
+var stepDownAttempts int
+
+// stepDownPreferred is the host --prefer resolves to
+var stepDownPreferred *config.Host
+
+// stepDownCmd represents the step-down command
+var stepDownCmd = &cobra.Command{
+	Use:         "step-down",
+	Annotations: mutating,
+	Short:       "Forces the active Vault server to step down",
+	Long: `Finds the active Vault server in each datacenter, forces it
+to step down and reports which host became active. With --prefer,
+step down is repeated until the preferred host is active. This
+requires a token`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		vaultToken, err := GetToken()
+		if err != nil {
+			log.Fatal("Error reading token: ", err)
+		}
+
+		if vaultToken == "" {
+			log.Fatal("Stepping down requires a token: See --help")
+		}
+
+		if stepDownAttempts < 1 {
+			log.Fatal("--attempts must be at least 1")
+		}
+
+		if stepDownWait <= 0 {
+			log.Fatal("--wait must be greater than 0")
+		}
+
+		allDCs := GetTargetDatacenters()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
+
+		// only the datacenter of the preferred host is stepped down
+		if stepDownPrefer != "" {
+			dc, host, err := findPreferredHost(allDCs, stepDownPrefer)
+			if err != nil {
+				log.Fatal(err)
+			}
+			allDCs = []config.Datacenter{dc}
+			stepDownPreferred = &host
+		}
+
+		wg := sync.WaitGroup{}
+		results := &ResultSet{}
+
+		for _, dc := range allDCs {
+			wg.Add(1)
+			log.WithFields(log.Fields{
+				"datacenter": dc.Name,
+			}).Debugln("Starting to process step down")
+
+			go ProcessStepDown(&wg, dc, configHelper, v.NewVaultHelper, vaultToken, StepDownHost, results)
+		}
+		wg.Wait()
+
+		renderResults(results)
+		exitWithResults(results, false)
+	},
+}
+
+type HostStepDownImpl func(*v.VaultHelper) error
+
+// findPreferredHost finds the datacenter and host --prefer names. It must name
+// exactly one host in the selected datacenters.
+func findPreferredHost(datacenters []config.Datacenter, name string) (config.Datacenter, config.Host, error) {
+
+	var foundDC config.Datacenter
+	var foundHost config.Host
+	found := 0
+	for _, dc := range datacenters {
+		for _, host := range dc.Hosts {
+			if host.Is(name) {
+				foundDC, foundHost = dc, host
+				found++
+			}
+		}
+	}
+
+	switch found {
+	case 0:
+		return foundDC, foundHost, fmt.Errorf("Preferred host %s is not in the selected datacenters", name)
+	case 1:
+		return foundDC, foundHost, nil
+	}
+
+	return foundDC, foundHost, fmt.Errorf("Preferred host %s matches %d hosts: use name:port", name, found)
+}
+
+// isPreferred checks if a host is the one --prefer resolved to
+func isPreferred(vaultHelper *v.VaultHelper) bool {
+	return stepDownPreferred != nil &&
+		vaultHelper.HostName == stepDownPreferred.Name &&
+		vaultHelper.Port == stepDownPreferred.Port
+}
+
+func ProcessStepDown(wg *sync.WaitGroup,
+	dc config.Datacenter,
+	configHelper *ConfigHelper,
+	vhGetter v.VaultHelperGetter,
+	vaultToken string,
+	stepDownHost HostStepDownImpl,
+	results *ResultSet) {
+
+	defer wg.Done()
+
+	caPath := configHelper.GetCAPath()
+
+	dcLogger := log.WithFields(log.Fields{"datacenter": dc.Name})
+	dcLogger.Debugln("Processing datacenter")
+
+	var vaultHelpers []*v.VaultHelper
+	for _, host := range dc.Hosts {
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		vaultHelper.Token = vaultToken
+		vaultHelpers = append(vaultHelpers, vaultHelper)
+	}
+
+	leader := findActiveNode(vaultHelpers)
+	if leader == nil {
+		dcLogger.Errorln("Unable to find the active node")
+		results.Add(HostResult{Datacenter: dc.Name, Error: "Unable to find the active node"})
+		return
+	}
+
+	if isPreferred(leader) {
+		dcLogger.WithFields(log.Fields{
+			"host": leader.HostName,
+		}).Infoln("Preferred host is already active")
+		hostResult := NewHostResult(leader)
+		hostResult.Initialized = true
+		hostResult.Message = "Preferred host is already active"
+		results.Add(hostResult)
+		return
+	}
+
+	for attempt := 1; attempt <= stepDownAttempts; attempt++ {
+		dcLogger.WithFields(log.Fields{
+			"host":    leader.HostName,
+			"attempt": attempt,
+		}).Infoln("Stepping down active node")
+
+		if err := stepDownHost(leader); err != nil {
+			dcLogger.WithFields(log.Fields{
+				"host":  leader.HostName,
+				"error": err,
+			}).Errorln("Error stepping down")
+			hostResult := NewHostResult(leader)
+			hostResult.SetError(err)
+			results.Add(hostResult)
+			return
+		}
+
+		newLeader := waitForNewActiveNode(vaultHelpers, leader, stepDownWait)
+		if newLeader == nil {
+			dcLogger.WithFields(log.Fields{
+				"previous": leader.HostName,
+				"wait":     stepDownWait,
+			}).Errorln("No new active node found")
+			hostResult := NewHostResult(leader)
+			hostResult.Error = "No new active node found after stepping down"
+			results.Add(hostResult)
+			return
+		}
+
+		dcLogger.WithFields(log.Fields{
+			"previous": leader.HostName,
+			"host":     newLeader.HostName,
+		}).Infoln("New active node")
+
+		if stepDownPreferred == nil || isPreferred(newLeader) {
+			hostResult := NewHostResult(newLeader)
+			hostResult.Initialized = true
+			hostResult.Message = "New active node"
+			results.Add(hostResult)
+			return
+		}
+
+		leader = newLeader
+	}
+
+	dcLogger.WithFields(log.Fields{
+		"prefer":   stepDownPrefer,
+		"attempts": stepDownAttempts,
+	}).Errorln("Preferred host did not become active")
+	hostResult := NewHostResult(leader)
+	hostResult.Initialized = true
+	hostResult.Error = "Preferred host did not become active"
+	results.Add(hostResult)
+}
+
+func StepDownHost(vaultHelper *v.VaultHelper) error {
+
+	client, err := vaultHelper.GetVaultClient()
+	if err != nil {
+		return err
+	}
+
+	return client.Sys().StepDown()
+}
+
+// findActiveNode asks every host whether it is the active node
+func findActiveNode(vaultHelpers []*v.VaultHelper) *v.VaultHelper {
+
+	var active *v.VaultHelper
+	var mutex sync.Mutex
+
+	hwg := sync.WaitGroup{}
+	for _, vaultHelper := range vaultHelpers {
+		hwg.Add(1)
+		go func(vaultHelper *v.VaultHelper) {
+			defer hwg.Done()
+
+			isSelf, err := isActiveNode(vaultHelper)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"host":  vaultHelper.HostName,
+					"error": err,
+				}).Debugln("Error getting leader")
+				return
+			}
+
+			if isSelf {
+				mutex.Lock()
+				active = vaultHelper
+				mutex.Unlock()
+			}
+		}(vaultHelper)
+	}
+	hwg.Wait()
+
+	return active
+}
+
+// waitForNewActiveNode polls the hosts until one other than previous is
+// active. Hosts are compared by helper, as several can share a name.
+func waitForNewActiveNode(vaultHelpers []*v.VaultHelper, previous *v.VaultHelper, wait time.Duration) *v.VaultHelper {
+
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+
+		active := findActiveNode(vaultHelpers)
+		if active != nil && active != previous {
+			return active
+		}
+	}
+
+	return nil
+}
+
+func isActiveNode(vaultHelper *v.VaultHelper) (bool, error) {
+
+	client, err := vaultHelper.GetVaultClient()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := client.Sys().Leader()
+	if err != nil {
+		return false, err
+	}
+
+	if result == nil {
+		return false, errors.New("empty leader response")
+	}
+
+	return result.IsSelf, nil
+}
+
+func init() {
+	RootCmd.AddCommand(stepDownCmd)
+
+	stepDownCmd.Flags().StringVar(&stepDownPrefer, "prefer", "", "Host to keep stepping down until it becomes active. Only its datacenter is stepped down")
+	stepDownCmd.Flags().DurationVar(&stepDownWait, "wait", 30*time.Second, "How long to wait for a new active node after each step down")
+	stepDownCmd.Flags().IntVar(&stepDownAttempts, "attempts", 5, "Maximum number of step downs when using --prefer")
+
+}
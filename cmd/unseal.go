@@ -21,6 +21,13 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
 	"github.com/hashicorp/vault/api"
 	v "github.com/jaxxstorm/hookpick/vault"
 	log "github.com/sirupsen/logrus"
@@ -33,19 +40,32 @@ import (
 	"github.com/jaxxstorm/hookpick/gpg"
 )
 
+var unsealMigrate bool
+
+// Messages UnsealHost reports, used to summarise the run
+const (
+	msgNotReady        = "Vault is not ready to be unsealed"
+	msgAlreadyUnsealed = "Vault is already unsealed"
+	msgUnsealed        = "Vault is unsealed"
+	msgNoKey           = "No key provided"
+	msgNotMigrating    = "Vault is not pending a seal migration"
+)
+
 // unsealCmd represents the unseal command
 var unsealCmd = &cobra.Command{
-	Use:   "unseal",
-	Short: "Unseal the vaults using the key providers",
+	Use:         "unseal",
+	Annotations: mutating,
+	Short:       "Unseal the vaults using the key providers",
 	Long: `Sends an unseal operationg to all vaults in the configuration file
 using the key provided`,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		allDCs := GetDatacenters()
-		configHelper := NewConfigHelper(GetSpecificDatacenter, GetCaPath, GetProtocol, GetGpgKey)
+		allDCs := GetTargets()
+		configHelper := NewConfigHelper(GetCaPath, GetGpgKey, GetTLSConfig)
 		gpgHelper := gpg.NewGPGHelper(gpg.Decrypt)
 
 		wg := sync.WaitGroup{}
+		results := &ResultSet{}
 
 		for _, dc := range allDCs {
 			wg.Add(1)
@@ -53,14 +73,19 @@ using the key provided`,
 				"datacenter": dc.Name,
 			}).Debugln("Starting to process Vault unseal")
 
-			go ProcessUnseal(&wg, dc, configHelper, v.NewVaultHelper, gpgHelper, GetVaultKeys, UnsealHost)
+			go ProcessUnseal(&wg, dc, configHelper, v.NewVaultHelper, gpgHelper, GetVaultKeys, UnsealHost, results)
 		}
 		wg.Wait()
+
+		printUnsealSummary(os.Stderr, SummariseUnseal(results.Sorted()))
+
+		renderResults(results)
+		exitWithResults(results, true)
 	},
 }
 
 type VaultKeyGetter func(config.Datacenter, ConfigKeyGetter, gpg.StringDecrypter) []string
-type HostSubmitImpl func(*sync.WaitGroup, *v.VaultHelper, []string) bool
+type HostSubmitImpl func(*sync.WaitGroup, *v.VaultHelper, []string, *ResultSet) bool
 
 func ProcessUnseal(wg *sync.WaitGroup,
 	dc config.Datacenter,
@@ -68,40 +93,31 @@ func ProcessUnseal(wg *sync.WaitGroup,
 	vhGetter v.VaultHelperGetter,
 	gpgHelper *gpg.GPGHelper,
 	vaultKeysGetter VaultKeyGetter,
-	unsealHost HostSubmitImpl) {
+	unsealHost HostSubmitImpl,
+	results *ResultSet) {
 
 	defer wg.Done()
 
-	specificDC := configHelper.GetDC()
 	caPath := configHelper.GetCAPath()
-	protocol := configHelper.GetURLScheme()
 
 	dcLogger := log.WithFields(log.Fields{"datacenter": dc.Name})
-
-	if specificDC != "" {
-		dcLogger = dcLogger.WithFields(
-			log.Fields{
-				"specified_dc": specificDC,
-			})
-	}
 	dcLogger.Debugln("Processing datacenter")
 
-	if specificDC == dc.Name || specificDC == "" {
+	vaultKeys := vaultKeysGetter(dc, configHelper.GetGPGKey, gpgHelper.Decrypt)
 
-		vaultKeys := vaultKeysGetter(dc, configHelper.GetGPGKey, gpgHelper.Decrypt)
-
-		hwg := sync.WaitGroup{}
-		for _, host := range dc.Hosts {
-			hwg.Add(1)
-			log.WithFields(log.Fields{
-				"host": host.Name,
-			}).Debugln("Processing host")
+	hwg := sync.WaitGroup{}
+	for _, host := range dc.Hosts {
+		hwg.Add(1)
+		log.WithFields(log.Fields{
+			"host": host.Name,
+		}).Debugln("Processing host")
 
-			vaultHelper := vhGetter(host.Name, caPath, protocol, host.Port, v.Status)
-			go unsealHost(&hwg, vaultHelper, vaultKeys)
-		}
-		hwg.Wait()
+		vaultHelper := vhGetter(host.Name, caPath, host.Protocol, host.Port, v.Status)
+		vaultHelper.Datacenter = dc.Name
+		vaultHelper.TLS = configHelper.GetTLS(dc, host)
+		go unsealHost(&hwg, vaultHelper, vaultKeys, results)
 	}
+	hwg.Wait()
 }
 
 func GetVaultKeys(dc config.Datacenter, gpgKeyGetter ConfigKeyGetter, keyDecrypter gpg.StringDecrypter) []string {
@@ -118,9 +134,12 @@ func GetVaultKeys(dc config.Datacenter, gpgKeyGetter ConfigKeyGetter, keyDecrypt
 	return vaultKeys
 }
 
-func UnsealHost(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys []string) bool {
+func UnsealHost(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys []string, results *ResultSet) bool {
 	defer wg.Done()
 
+	hostResult := NewHostResult(vaultHelper)
+	defer func() { results.Add(hostResult) }()
+
 	log.WithFields(log.Fields{
 		"host": vaultHelper.HostName,
 	}).Debugln("Starting unseal")
@@ -132,58 +151,207 @@ func UnsealHost(wg *sync.WaitGroup, vaultHelper *v.VaultHelper, vaultKeys []stri
 			"port":  vaultHelper.Port,
 			"error": err,
 		}).Errorln("Error creating Vault API Client")
+		hostResult.SetUnreachable(err)
+		return false
 	}
 
 	// get the current status
-	_, init := vaultHelper.GetStatus(client)
+	state, err := vaultHelper.GetStatus(client)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  vaultHelper.HostName,
+			"error": err,
+		}).Errorln("Error getting vault status")
+		hostResult.SetUnreachable(err)
+		return false
+	}
+	sealed, init := state.Sealed(), state.Initialized()
+	hostResult.Sealed = sealed
+	hostResult.Initialized = init
 	if !init {
 		// sad times, not ready to be unsealed
 		log.WithFields(log.Fields{
 			"host": vaultHelper.HostName,
 		}).Errorln("Vault is not ready to be unsealed")
+		hostResult.Message = msgNotReady
 		return init
 	}
 
+	// there's nothing to do if it's already unsealed
+	if !sealed {
+		log.WithFields(log.Fields{
+			"host": vaultHelper.HostName,
+		}).Infoln("Vault is already unsealed")
+		hostResult.Message = msgAlreadyUnsealed
+		return true
+	}
+
+	// a seal migration is only in progress if vault was started with both seals configured
+	if unsealMigrate {
+		sealStatus, err := client.Sys().SealStatus()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"host":  vaultHelper.HostName,
+				"error": err,
+			}).Errorln("Error getting seal status")
+			hostResult.SetError(err)
+			return false
+		}
+
+		hostResult.Migration = sealStatus.Migration
+		if !sealStatus.Migration {
+			log.WithFields(log.Fields{
+				"host":      vaultHelper.HostName,
+				"migration": sealStatus.Migration,
+			}).Errorln("Vault is not pending a seal migration, refusing to unseal with --migrate")
+			hostResult.Message = msgNotMigrating
+			return false
+		}
+	}
+
 	if len(vaultKeys) > 0 {
 		var vaultStatus *api.SealStatusResponse
+		var unsealErr error
+		rejected := 0
 		for _, vaultKey := range vaultKeys {
-			result, err := client.Sys().Unseal(vaultKey)
+			result, err := client.Sys().UnsealWithOptions(&api.UnsealOpts{
+				Key:     vaultKey,
+				Migrate: unsealMigrate,
+			})
 			// error while unsealing
 			if err != nil {
 				log.WithFields(log.Fields{
-					"host": vaultHelper.HostName,
+					"host":  vaultHelper.HostName,
+					"error": err,
 				}).Errorln("Error running unseal operation")
+				unsealErr = err
+				rejected++
+				continue
 			}
 			vaultStatus = result
 		}
 
+		// a rejected key is a failure even if the others were accepted
+		if rejected > 0 {
+			hostResult.SetError(fmt.Errorf("%d of %d keys rejected: %s", rejected, len(vaultKeys), unsealErr))
+		}
+
+		if vaultStatus == nil {
+			return false
+		}
+
+		hostResult.Sealed = vaultStatus.Sealed
+		hostResult.Progress = vaultStatus.Progress
+		hostResult.Threshold = vaultStatus.T
+		hostResult.Migration = vaultStatus.Migration
+
 		// if it's still sealed, print the progress
 		if vaultStatus.Sealed == true {
 			log.WithFields(log.Fields{
 				"host":      vaultHelper.HostName,
 				"progress":  vaultStatus.Progress,
 				"threshold": vaultStatus.T,
+				"migration": vaultStatus.Migration,
 			}).Infoln("Unseal operation performed")
+			hostResult.Message = "Unseal operation performed"
 			// otherwise, tell us it's unsealed!
 		} else {
 			log.WithFields(log.Fields{
 				"host":      vaultHelper.HostName,
 				"progress":  vaultStatus.Progress,
 				"threshold": vaultStatus.T,
+				"migration": vaultStatus.Migration,
 			}).Infoln("Vault is unsealed!")
+			hostResult.Message = msgUnsealed
 		}
 	} else {
 		log.WithFields(log.Fields{
 			"host": vaultHelper.HostName,
 		}).Errorln("No Key Provided")
+		hostResult.Message = msgNoKey
 	}
 
 	return true
 }
 
+// UnsealSummary counts the outcome of an unseal run in a datacenter
+type UnsealSummary struct {
+	Datacenter      string
+	Hosts           int
+	AlreadyUnsealed int
+	Unsealed        int
+	// StillSealed holds the progress of each host that is still sealed
+	StillSealed []string
+	// Skipped counts sealed hosts no unseal was attempted on
+	Skipped        int
+	NotInitialized int
+	Unreachable    int
+	Failed         int
+}
+
+// SummariseUnseal counts the outcome for each datacenter in the results
+func SummariseUnseal(results []HostResult) []UnsealSummary {
+
+	var summaries []UnsealSummary
+	index := make(map[string]int)
+
+	for _, result := range results {
+		i, ok := index[result.Datacenter]
+		if !ok {
+			i = len(summaries)
+			index[result.Datacenter] = i
+			summaries = append(summaries, UnsealSummary{Datacenter: result.Datacenter})
+		}
+		summary := &summaries[i]
+		summary.Hosts++
+
+		switch {
+		case result.Unreachable:
+			summary.Unreachable++
+		case !result.Initialized:
+			summary.NotInitialized++
+		case result.Error != "":
+			summary.Failed++
+		case result.Message == msgAlreadyUnsealed:
+			summary.AlreadyUnsealed++
+		case result.Message == msgNoKey, result.Message == msgNotMigrating:
+			summary.Skipped++
+		case result.Sealed:
+			summary.StillSealed = append(summary.StillSealed, fmt.Sprintf("%d/%d", result.Progress, result.Threshold))
+		default:
+			summary.Unsealed++
+		}
+	}
+
+	return summaries
+}
+
+// printUnsealSummary writes a table of the outcome in each datacenter
+func printUnsealSummary(w io.Writer, summaries []UnsealSummary) {
+
+	if len(summaries) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATACENTER\tHOSTS\tALREADY UNSEALED\tNEWLY UNSEALED\tSTILL SEALED\tSKIPPED\tNOT INITIALIZED\tUNREACHABLE\tFAILED")
+	for _, summary := range summaries {
+		stillSealed := strconv.Itoa(len(summary.StillSealed))
+		if len(summary.StillSealed) > 0 {
+			stillSealed += " (" + strings.Join(summary.StillSealed, ", ") + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%d\t%d\t%d\t%d\n",
+			summary.Datacenter, summary.Hosts, summary.AlreadyUnsealed, summary.Unsealed,
+			stillSealed, summary.Skipped, summary.NotInitialized, summary.Unreachable, summary.Failed)
+	}
+	tw.Flush()
+}
+
 func init() {
 	RootCmd.AddCommand(unsealCmd)
 
+	unsealCmd.Flags().BoolVar(&unsealMigrate, "migrate", false, "Unseal with migrate=true to complete a seal migration")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
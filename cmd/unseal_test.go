@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSummariseUnseal(t *testing.T) {
+
+	results := []HostResult{
+		{Datacenter: "dc1", Host: "vault-1", Initialized: true, Message: msgAlreadyUnsealed},
+		{Datacenter: "dc1", Host: "vault-2", Initialized: true, Message: msgUnsealed},
+		{Datacenter: "dc1", Host: "vault-3", Initialized: true, Sealed: true, Progress: 1, Threshold: 3, Message: "Unseal operation performed"},
+		{Datacenter: "dc1", Host: "vault-4", Initialized: true, Sealed: true, Progress: 2, Threshold: 3, Error: "1 of 2 keys rejected: bad key"},
+		{Datacenter: "dc1", Host: "vault-5", Unreachable: true, Error: "connection refused"},
+		{Datacenter: "dc2", Host: "vault-6", Message: msgNotReady},
+		{Datacenter: "dc2", Host: "vault-7", Initialized: true, Sealed: true, Message: msgNoKey},
+		{Datacenter: "dc2", Host: "vault-8", Initialized: true, Sealed: true, Message: msgNotMigrating},
+		{Datacenter: "dc1", Host: "vault-9", Initialized: true, Sealed: true, Progress: 2, Threshold: 3, Message: "Unseal operation performed"},
+	}
+
+	want := []UnsealSummary{
+		{
+			Datacenter:      "dc1",
+			Hosts:           6,
+			AlreadyUnsealed: 1,
+			Unsealed:        1,
+			StillSealed:     []string{"1/3", "2/3"},
+			Unreachable:     1,
+			Failed:          1,
+		},
+		{
+			Datacenter:     "dc2",
+			Hosts:          3,
+			Skipped:        2,
+			NotInitialized: 1,
+		},
+	}
+
+	if got := SummariseUnseal(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("SummariseUnseal() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := SummariseUnseal(nil); got != nil {
+		t.Errorf("SummariseUnseal(nil) = %+v, want nil", got)
+	}
+}
+
+func TestPrintUnsealSummary(t *testing.T) {
+
+	var out bytes.Buffer
+	printUnsealSummary(&out, []UnsealSummary{
+		{Datacenter: "dc1", Hosts: 3, Unsealed: 1, StillSealed: []string{"1/3"}, Skipped: 1},
+	})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("printUnsealSummary() printed %q, want a header and one row", out.String())
+	}
+	if got := strings.Fields(lines[1]); !reflect.DeepEqual(got, []string{"dc1", "3", "0", "1", "1", "(1/3)", "1", "0", "0", "0"}) {
+		t.Errorf("printUnsealSummary() row = %q", lines[1])
+	}
+
+	out.Reset()
+	printUnsealSummary(&out, nil)
+	if out.Len() != 0 {
+		t.Errorf("printUnsealSummary(nil) printed %q, want nothing", out.String())
+	}
+}
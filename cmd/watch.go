@@ -0,0 +1,184 @@
+// Copyright © 2017 Lee Briggs <lee@leebriggs.co.uk>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	clearScreen    = "\033[H\033[2J"
+	highlightStart = "\033[1;33m"
+	highlightEnd   = "\033[0m"
+	// changeLogSize : how many changes are kept under the table
+	changeLogSize = 20
+)
+
+// watchStatus polls every host until interrupted, redrawing the status table
+// and logging every seal or leader change it sees
+func watchStatus(interval time.Duration) {
+
+	if outputFormat != "" && outputFormat != "table" {
+		log.Fatal("--watch only supports table output")
+	}
+
+	if interval <= 0 {
+		log.Fatal("--interval must be greater than 0")
+	}
+
+	// log lines would scroll the table off the screen, but fatal errors
+	// like a bad config still need to be seen
+	if !debug {
+		log.SetLevel(log.FatalLevel)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previous map[string]HostResult
+	var changeLog []string
+	var results *ResultSet
+
+	for {
+		results = collectStatus()
+		sorted := results.Sorted()
+		now := time.Now()
+
+		current := make(map[string]HostResult)
+		changed := make(map[string]bool)
+		for _, result := range sorted {
+			key := watchKey(result)
+			current[key] = result
+
+			if previous == nil {
+				continue
+			}
+
+			for _, change := range statusChanges(previous[key], result) {
+				changed[key] = true
+				changeLog = append(changeLog, fmt.Sprintf("%s  %s  %s", now.Format("15:04:05"), key, change))
+			}
+		}
+		for key := range previous {
+			if _, ok := current[key]; !ok {
+				changeLog = append(changeLog, fmt.Sprintf("%s  %s  no longer reported", now.Format("15:04:05"), key))
+			}
+		}
+		previous = current
+
+		if len(changeLog) > changeLogSize {
+			changeLog = changeLog[len(changeLog)-changeLogSize:]
+		}
+
+		if err := drawWatch(sorted, changed, changeLog, now, interval); err != nil {
+			log.Fatal("Error rendering output: ", err)
+		}
+
+		select {
+		case <-signals:
+			exitWithResults(results, true)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drawWatch redraws the whole screen with changed rows highlighted
+func drawWatch(results []HostResult, changed map[string]bool, changeLog []string, now time.Time, interval time.Duration) error {
+
+	var table bytes.Buffer
+	if err := renderTable(&table, results); err != nil {
+		return err
+	}
+
+	// the first line of the table is the header, then one line per result
+	lines := strings.Split(strings.TrimRight(table.String(), "\n"), "\n")
+	for i, result := range results {
+		if changed[watchKey(result)] && i+1 < len(lines) {
+			lines[i+1] = highlightStart + lines[i+1] + highlightEnd
+		}
+	}
+
+	var screen bytes.Buffer
+	screen.WriteString(clearScreen)
+	fmt.Fprintf(&screen, "Every %s: hookpick status    %s\n\n", interval, now.Format(time.RFC1123))
+	screen.WriteString(strings.Join(lines, "\n"))
+	screen.WriteString("\n")
+
+	if len(changeLog) > 0 {
+		screen.WriteString("\nChanges:\n")
+		for _, change := range changeLog {
+			screen.WriteString(change + "\n")
+		}
+	}
+
+	_, err := os.Stdout.Write(screen.Bytes())
+	return err
+}
+
+// watchKey identifies a host across polls
+func watchKey(result HostResult) string {
+	return result.Datacenter + "/" + result.Host + ":" + result.Port
+}
+
+// statusChanges describes how a host's seal or leader state changed between polls
+func statusChanges(before, after HostResult) []string {
+
+	// a host that wasn't in the previous poll
+	if before.Host == "" {
+		return []string{"first seen"}
+	}
+
+	var changes []string
+
+	changeOf := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s %s -> %s", field, from, to))
+		}
+	}
+
+	changeOf("reachable", strconv.FormatBool(!before.Unreachable), strconv.FormatBool(!after.Unreachable))
+	changeOf("initialized", strconv.FormatBool(before.Initialized), strconv.FormatBool(after.Initialized))
+	changeOf("sealed", strconv.FormatBool(before.Sealed), strconv.FormatBool(after.Sealed))
+	changeOf("role", valueOrNone(before.Role), valueOrNone(after.Role))
+	changeOf("leader", valueOrNone(before.LeaderAddress), valueOrNone(after.LeaderAddress))
+
+	return changes
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Requirement is a single term of a selector
+type Requirement struct {
+	Key   string
+	Value string
+	// Op is "=", "!=" or "" for a bare tag
+	Op string
+}
+
+// Selector matches hosts by their tags and labels. Every requirement must
+// match.
+type Selector []Requirement
+
+// ParseSelector parses a selector like "role=voter,zone!=b,canary". Terms
+// with = or != match labels, bare terms match tags.
+func ParseSelector(selector string) (Selector, error) {
+
+	var parsed Selector
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var requirement Requirement
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			requirement = Requirement{Key: parts[0], Value: parts[1], Op: "!="}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			requirement = Requirement{Key: parts[0], Value: parts[1], Op: "="}
+		default:
+			requirement = Requirement{Key: term}
+		}
+
+		requirement.Key = strings.TrimSpace(requirement.Key)
+		requirement.Value = strings.TrimSpace(requirement.Value)
+		if requirement.Key == "" {
+			return nil, fmt.Errorf("invalid selector term %q", term)
+		}
+
+		parsed = append(parsed, requirement)
+	}
+
+	return parsed, nil
+}
+
+// Matches checks the selector against a set of tags and labels
+func (s Selector) Matches(tags []string, labels map[string]string) bool {
+
+	for _, requirement := range s {
+		switch requirement.Op {
+		case "=":
+			if value, ok := labels[requirement.Key]; !ok || value != requirement.Value {
+				return false
+			}
+		case "!=":
+			if labels[requirement.Key] == requirement.Value {
+				return false
+			}
+		default:
+			if !contains(tags, requirement.Key) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Target picks the datacenters and hosts a command operates on
+type Target struct {
+	// Datacenters limits the target to datacenters matching these names or
+	// glob patterns, if any are given
+	Datacenters []string
+	// ExcludeDatacenters leaves out datacenters matching these names or
+	// glob patterns
+	ExcludeDatacenters []string
+	// Hosts limits the target to hosts with these names, or name:port
+	Hosts []string
+	// Selector limits the target to hosts whose tags and labels match
+	Selector Selector
+}
+
+// Filter returns the datacenters and hosts the target selects. Datacenters
+// with no hosts selected are left out. Every datacenter pattern and host
+// name given must match something in the config file.
+func (t Target) Filter(datacenters []Datacenter) ([]Datacenter, error) {
+
+	if err := t.checkDatacenters(datacenters); err != nil {
+		return nil, err
+	}
+
+	hostFound := make(map[string]bool)
+	// hostExcluded holds the datacenter of named hosts that were left out
+	// with their datacenter
+	hostExcluded := make(map[string]string)
+
+	var selected []Datacenter
+	for _, dc := range datacenters {
+		if (len(t.Datacenters) > 0 && !matchesAny(t.Datacenters, dc.Name)) ||
+			matchesAny(t.ExcludeDatacenters, dc.Name) {
+			for _, host := range dc.Hosts {
+				for _, name := range t.Hosts {
+					if host.Is(name) {
+						hostExcluded[name] = dc.Name
+					}
+				}
+			}
+			continue
+		}
+
+		var hosts []Host
+		for _, host := range dc.Hosts {
+			if len(t.Hosts) > 0 {
+				matched := false
+				for _, name := range t.Hosts {
+					if host.Is(name) {
+						hostFound[name] = true
+						matched = true
+					}
+				}
+				if !matched {
+					continue
+				}
+			}
+
+			tags, labels := dc.HostTagsAndLabels(host)
+			if !t.Selector.Matches(tags, labels) {
+				continue
+			}
+
+			hosts = append(hosts, host)
+		}
+
+		if len(hosts) == 0 {
+			continue
+		}
+
+		dc.Hosts = hosts
+		selected = append(selected, dc)
+	}
+
+	for _, name := range t.Hosts {
+		if hostFound[name] {
+			continue
+		}
+		if dcName, ok := hostExcluded[name]; ok {
+			return nil, fmt.Errorf("host %q is in datacenter %s, which is excluded", name, dcName)
+		}
+		return nil, fmt.Errorf("host %q is not in the config file", name)
+	}
+
+	return selected, nil
+}
+
+// checkDatacenters makes sure every datacenter pattern is valid and matches
+// at least one datacenter, so a typo doesn't silently select nothing
+func (t Target) checkDatacenters(datacenters []Datacenter) error {
+
+	patterns := append(append([]string{}, t.Datacenters...), t.ExcludeDatacenters...)
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid datacenter pattern %q: %s", pattern, err)
+		}
+
+		found := false
+		for _, dc := range datacenters {
+			if matchesAny([]string{pattern}, dc.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no datacenter in the config file matches %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// matchesAny checks a name against a list of names or glob patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Is checks if the host is the one named, by its name, its name as written
+// in the config file, or its address. A name can also be given with the port,
+// as name:port.
+func (h Host) Is(name string) bool {
+	for _, candidate := range []string{h.Name, h.configuredName} {
+		if candidate == "" {
+			continue
+		}
+		if strings.EqualFold(candidate, name) || strings.EqualFold(candidate+":"+h.Port, name) {
+			return true
+		}
+	}
+	return h.Address != "" && strings.EqualFold(strings.TrimSuffix(h.Address, "/"), strings.TrimSuffix(name, "/"))
+}
+
+// HostTagsAndLabels returns a host's tags and labels, including those it
+// inherits from the datacenter. Host labels override datacenter labels.
+func (dc Datacenter) HostTagsAndLabels(host Host) ([]string, map[string]string) {
+
+	var tags []string
+	tags = append(tags, dc.Tags...)
+	tags = append(tags, host.Tags...)
+
+	labels := make(map[string]string)
+	for key, value := range dc.Labels {
+		labels[key] = value
+	}
+	for key, value := range host.Labels {
+		labels[key] = value
+	}
+
+	return tags, labels
+}
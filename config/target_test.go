@@ -0,0 +1,320 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+
+	tests := []struct {
+		selector string
+		want     Selector
+		wantErr  bool
+	}{
+		{selector: "", want: nil},
+		{selector: "canary", want: Selector{{Key: "canary"}}},
+		{selector: "role=voter", want: Selector{{Key: "role", Value: "voter", Op: "="}}},
+		{selector: "zone!=b", want: Selector{{Key: "zone", Value: "b", Op: "!="}}},
+		{
+			selector: " role = voter , zone!=b,, canary ",
+			want: Selector{
+				{Key: "role", Value: "voter", Op: "="},
+				{Key: "zone", Value: "b", Op: "!="},
+				{Key: "canary"},
+			},
+		},
+		{selector: "role=", want: Selector{{Key: "role", Value: "", Op: "="}}},
+		{selector: "=voter", wantErr: true},
+		{selector: "!=b", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSelector(test.selector)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseSelector(%q) error = %v, wantErr %v", test.selector, err, test.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseSelector(%q) = %v, want %v", test.selector, got, test.want)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+
+	tags := []string{"canary"}
+	labels := map[string]string{"role": "voter", "zone": "a"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"canary", true},
+		{"stable", false},
+		{"role=voter", true},
+		{"role=standby", false},
+		{"missing=", false},
+		{"zone!=b", true},
+		{"zone!=a", false},
+		{"missing!=a", true},
+		{"role=voter,zone=a,canary", true},
+		{"role=voter,zone=b", false},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseSelector(test.selector)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q) error = %v", test.selector, err)
+		}
+		if got := selector.Matches(tags, labels); got != test.want {
+			t.Errorf("%q.Matches() = %v, want %v", test.selector, got, test.want)
+		}
+	}
+}
+
+func TestHostIs(t *testing.T) {
+
+	dc := Datacenter{
+		Name:   "dc1",
+		Port:   "8200",
+		Domain: "example.com",
+		Hosts: []Host{
+			{Name: "vault-1"},
+			{Address: "https://10.0.0.2:8300"},
+		},
+	}
+	if err := dc.Resolve("https"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	named, addressed := dc.Hosts[0], dc.Hosts[1]
+
+	tests := []struct {
+		host Host
+		name string
+		want bool
+	}{
+		{named, "vault-1", true},
+		{named, "VAULT-1", true},
+		{named, "vault-1:8200", true},
+		{named, "vault-1.example.com", true},
+		{named, "vault-1.example.com:8200", true},
+		{named, "vault-1:8300", false},
+		{named, "vault-2", false},
+		{addressed, "10.0.0.2", true},
+		{addressed, "10.0.0.2:8300", true},
+		{addressed, "https://10.0.0.2:8300", true},
+		{addressed, "https://10.0.0.2:8300/", true},
+		{addressed, "https://10.0.0.2:8200", false},
+	}
+
+	for _, test := range tests {
+		if got := test.host.Is(test.name); got != test.want {
+			t.Errorf("%s:%s Is(%q) = %v, want %v", test.host.Name, test.host.Port, test.name, got, test.want)
+		}
+	}
+}
+
+// targetDatacenters is a small fleet for the Filter tests
+func targetDatacenters() []Datacenter {
+	return []Datacenter{
+		{
+			Name:   "eu-west",
+			Labels: map[string]string{"zone": "a"},
+			Hosts: []Host{
+				{Name: "vault-1", Port: "8200", Labels: map[string]string{"role": "voter"}},
+				{Name: "vault-2", Port: "8200", Tags: []string{"canary"}, Labels: map[string]string{"zone": "b"}},
+			},
+		},
+		{
+			Name: "eu-north",
+			Hosts: []Host{
+				{Name: "vault-3", Port: "8200", Labels: map[string]string{"role": "voter"}},
+			},
+		},
+		{
+			Name: "us-east",
+			Hosts: []Host{
+				{Name: "vault-4", Port: "8200"},
+				{Name: "vault-4", Port: "8201", Tags: []string{"canary"}},
+			},
+		},
+	}
+}
+
+// selectedHosts lists the hosts Filter selected as datacenter/host:port
+func selectedHosts(datacenters []Datacenter) []string {
+	var hosts []string
+	for _, dc := range datacenters {
+		for _, host := range dc.Hosts {
+			hosts = append(hosts, dc.Name+"/"+host.Name+":"+host.Port)
+		}
+	}
+	return hosts
+}
+
+func TestTargetFilterHosts(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		hosts    []string
+		selector string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name: "everything",
+			want: []string{"eu-west/vault-1:8200", "eu-west/vault-2:8200", "eu-north/vault-3:8200", "us-east/vault-4:8200", "us-east/vault-4:8201"},
+		},
+		{
+			name:  "by name",
+			hosts: []string{"vault-1", "vault-4"},
+			want:  []string{"eu-west/vault-1:8200", "us-east/vault-4:8200", "us-east/vault-4:8201"},
+		},
+		{
+			name:  "by name and port",
+			hosts: []string{"vault-4:8201"},
+			want:  []string{"us-east/vault-4:8201"},
+		},
+		{
+			name:    "unknown host",
+			hosts:   []string{"vault-1", "vault-9"},
+			wantErr: `host "vault-9" is not in the config file`,
+		},
+		{
+			name:     "tag",
+			selector: "canary",
+			want:     []string{"eu-west/vault-2:8200", "us-east/vault-4:8201"},
+		},
+		{
+			name:     "inherited label",
+			selector: "zone=a",
+			want:     []string{"eu-west/vault-1:8200"},
+		},
+		{
+			name:     "labels",
+			selector: "role=voter,zone!=a",
+			want:     []string{"eu-north/vault-3:8200"},
+		},
+		{
+			name:     "host and selector",
+			hosts:    []string{"vault-1", "vault-2"},
+			selector: "canary",
+			want:     []string{"eu-west/vault-2:8200"},
+		},
+		{
+			name:     "nothing matches",
+			selector: "missing",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selector, err := ParseSelector(test.selector)
+			if err != nil {
+				t.Fatalf("ParseSelector(%q) error = %v", test.selector, err)
+			}
+
+			target := Target{Hosts: test.hosts, Selector: selector}
+			got, err := target.Filter(targetDatacenters())
+			checkFilter(t, got, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func checkFilter(t *testing.T, got []Datacenter, err error, want []string, wantErr string) {
+	t.Helper()
+
+	if wantErr != "" {
+		if err == nil || err.Error() != wantErr {
+			t.Fatalf("Filter() error = %v, want %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+
+	if hosts := selectedHosts(got); !reflect.DeepEqual(hosts, want) {
+		t.Errorf("Filter() = %v, want %v", hosts, want)
+	}
+}
+
+func TestTargetFilterDatacenters(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		hosts   []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "by name",
+			include: []string{"eu-north"},
+			want:    []string{"eu-north/vault-3:8200"},
+		},
+		{
+			name:    "glob",
+			include: []string{"eu-*"},
+			want:    []string{"eu-west/vault-1:8200", "eu-west/vault-2:8200", "eu-north/vault-3:8200"},
+		},
+		{
+			name:    "several",
+			include: []string{"eu-west", "us-*"},
+			want:    []string{"eu-west/vault-1:8200", "eu-west/vault-2:8200", "us-east/vault-4:8200", "us-east/vault-4:8201"},
+		},
+		{
+			name:    "exclude",
+			exclude: []string{"eu-west"},
+			want:    []string{"eu-north/vault-3:8200", "us-east/vault-4:8200", "us-east/vault-4:8201"},
+		},
+		{
+			name:    "glob and exclude",
+			include: []string{"eu-*"},
+			exclude: []string{"*-west"},
+			want:    []string{"eu-north/vault-3:8200"},
+		},
+		{
+			name:    "everything excluded",
+			exclude: []string{"*"},
+		},
+		{
+			name:    "no match",
+			include: []string{"ap-*"},
+			wantErr: `no datacenter in the config file matches "ap-*"`,
+		},
+		{
+			name:    "exclude no match",
+			exclude: []string{"eu-sotuh"},
+			wantErr: `no datacenter in the config file matches "eu-sotuh"`,
+		},
+		{
+			name:    "invalid pattern",
+			include: []string{"eu-["},
+			wantErr: `invalid datacenter pattern "eu-[": syntax error in pattern`,
+		},
+		{
+			name:    "host in excluded datacenter",
+			exclude: []string{"us-east"},
+			hosts:   []string{"vault-4"},
+			wantErr: `host "vault-4" is in datacenter us-east, which is excluded`,
+		},
+		{
+			name:    "host in unselected datacenter",
+			include: []string{"eu-*"},
+			hosts:   []string{"vault-1", "vault-4:8201"},
+			wantErr: `host "vault-4:8201" is in datacenter us-east, which is excluded`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := Target{Datacenters: test.include, ExcludeDatacenters: test.exclude, Hosts: test.hosts}
+			got, err := target.Filter(targetDatacenters())
+			checkFilter(t, got, err, test.want, test.wantErr)
+		})
+	}
+}
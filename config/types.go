@@ -1,19 +1,128 @@
 package config
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
 // Datacenter struct
 type Datacenter struct {
-	Name  string
-	Keys  []Key
-	Hosts []Host
+	Name     string
+	Keys     []Key
+	Hosts    []Host
+	Protocol string
+	Port     string
+	Domain   string
+	Tags     []string
+	Labels   map[string]string
+	TLS      `mapstructure:",squash"`
 }
 
 // Host struct
 type Host struct {
-	Name string
-	Port string
+	Name     string
+	Port     string
+	Protocol string
+	Address  string
+	Tags     []string
+	Labels   map[string]string
+	TLS      `mapstructure:",squash"`
+
+	// configuredName is the name as written in the config file, before
+	// Resolve appends the domain or takes the name from the address
+	configuredName string
+}
+
+// Resolve fills in the name, port and protocol of every host in the
+// datacenter. A host's address wins, then its own settings, then the
+// datacenter's, then the given default protocol.
+// Short host names, but not addresses, have the datacenter's domain appended.
+// Every host must end up with a port.
+func (dc *Datacenter) Resolve(defaultProtocol string) error {
+
+	for i := range dc.Hosts {
+		host := &dc.Hosts[i]
+		host.configuredName = host.Name
+
+		if host.Address != "" {
+			address, err := url.Parse(host.Address)
+			if err != nil {
+				return fmt.Errorf("datacenter %s: invalid address %q: %s", dc.Name, host.Address, err)
+			}
+			if address.Scheme == "" || address.Hostname() == "" {
+				return fmt.Errorf("datacenter %s: address %q must be a URL like https://vault-1:8200", dc.Name, host.Address)
+			}
+			host.Protocol = address.Scheme
+			host.Name = address.Hostname()
+			if address.Port() != "" {
+				host.Port = address.Port()
+			}
+		}
+
+		if host.Name == "" {
+			return fmt.Errorf("datacenter %s: host %d has no name or address", dc.Name, i+1)
+		}
+
+		if dc.Domain != "" && host.Address == "" && !strings.Contains(host.Name, ".") {
+			host.Name = host.Name + "." + strings.TrimPrefix(dc.Domain, ".")
+		}
+
+		host.Port = firstSet(host.Port, dc.Port)
+		if host.Port == "" {
+			return fmt.Errorf("datacenter %s: host %s has no port, set one on the host, its address or the datacenter", dc.Name, host.Name)
+		}
+		host.Protocol = firstSet(host.Protocol, dc.Protocol, defaultProtocol)
+	}
+
+	return nil
+}
+
+func firstSet(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
 }
 
 // Key struct
 type Key struct {
 	Key string
 }
+
+// TLS struct holds the settings used to connect to a vault over TLS. They can
+// be set globally, per datacenter and per host.
+type TLS struct {
+	CACert        string `mapstructure:"ca_cert"`
+	CAPath        string `mapstructure:"ca_path"`
+	ClientCert    string `mapstructure:"client_cert"`
+	ClientKey     string `mapstructure:"client_key"`
+	TLSServerName string `mapstructure:"tls_server_name"`
+	TLSSkipVerify *bool  `mapstructure:"tls_skip_verify"`
+}
+
+// Merge returns the settings with any set in override taking precedence. The
+// CA and the client certificate are each replaced as a pair, so a host can
+// swap a datacenter's ca_cert for a ca_path.
+func (t TLS) Merge(override TLS) TLS {
+	merged := t
+
+	if override.CACert != "" || override.CAPath != "" {
+		merged.CACert = override.CACert
+		merged.CAPath = override.CAPath
+	}
+	if override.ClientCert != "" || override.ClientKey != "" {
+		merged.ClientCert = override.ClientCert
+		merged.ClientKey = override.ClientKey
+	}
+	if override.TLSServerName != "" {
+		merged.TLSServerName = override.TLSServerName
+	}
+	if override.TLSSkipVerify != nil {
+		merged.TLSSkipVerify = override.TLSSkipVerify
+	}
+
+	return merged
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTLSMerge(t *testing.T) {
+
+	yes, no := true, false
+
+	global := TLS{
+		CACert:        "/etc/ssl/global-ca.pem",
+		ClientCert:    "/etc/ssl/global.pem",
+		ClientKey:     "/etc/ssl/global-key.pem",
+		TLSServerName: "vault.example.com",
+		TLSSkipVerify: &no,
+	}
+
+	tests := []struct {
+		name     string
+		override TLS
+		want     TLS
+	}{
+		{
+			name:     "nothing set",
+			override: TLS{},
+			want:     global,
+		},
+		{
+			name:     "ca path replaces ca cert",
+			override: TLS{CAPath: "/etc/ssl/dc1"},
+			want: TLS{
+				CAPath:        "/etc/ssl/dc1",
+				ClientCert:    "/etc/ssl/global.pem",
+				ClientKey:     "/etc/ssl/global-key.pem",
+				TLSServerName: "vault.example.com",
+				TLSSkipVerify: &no,
+			},
+		},
+		{
+			name:     "client cert replaced as a pair",
+			override: TLS{ClientCert: "/etc/ssl/host.pem"},
+			want: TLS{
+				CACert:        "/etc/ssl/global-ca.pem",
+				ClientCert:    "/etc/ssl/host.pem",
+				TLSServerName: "vault.example.com",
+				TLSSkipVerify: &no,
+			},
+		},
+		{
+			name:     "server name and skip verify",
+			override: TLS{TLSServerName: "vault-1.example.com", TLSSkipVerify: &yes},
+			want: TLS{
+				CACert:        "/etc/ssl/global-ca.pem",
+				ClientCert:    "/etc/ssl/global.pem",
+				ClientKey:     "/etc/ssl/global-key.pem",
+				TLSServerName: "vault-1.example.com",
+				TLSSkipVerify: &yes,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := global.Merge(test.override); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDatacenterResolve(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		dc      Datacenter
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "default protocol",
+			dc:   Datacenter{Hosts: []Host{{Name: "vault-1", Port: "8200"}}},
+			want: []string{"https://vault-1:8200"},
+		},
+		{
+			name: "datacenter defaults",
+			dc: Datacenter{Protocol: "http", Port: "8300", Hosts: []Host{
+				{Name: "vault-1"},
+				{Name: "vault-2", Protocol: "https", Port: "8400"},
+			}},
+			want: []string{"http://vault-1:8300", "https://vault-2:8400"},
+		},
+		{
+			name:    "no port",
+			dc:      Datacenter{Name: "dc1", Domain: "example.com", Hosts: []Host{{Name: "vault-1"}}},
+			wantErr: "datacenter dc1: host vault-1.example.com has no port, set one on the host, its address or the datacenter",
+		},
+		{
+			name: "domain",
+			dc: Datacenter{Domain: ".example.com", Port: "8200", Hosts: []Host{
+				{Name: "vault-1"},
+				{Name: "vault-2.other.com"},
+			}},
+			want: []string{"https://vault-1.example.com:8200", "https://vault-2.other.com:8200"},
+		},
+		{
+			name: "address",
+			dc: Datacenter{Domain: "example.com", Protocol: "https", Port: "8200", Hosts: []Host{
+				{Address: "http://10.0.0.1:8300"},
+				{Name: "ignored", Address: "http://vault-2"},
+			}},
+			want: []string{"http://10.0.0.1:8300", "http://vault-2:8200"},
+		},
+		{
+			name:    "address without scheme",
+			dc:      Datacenter{Name: "dc1", Hosts: []Host{{Address: "vault-1:8200"}}},
+			wantErr: `datacenter dc1: address "vault-1:8200" must be a URL like https://vault-1:8200`,
+		},
+		{
+			name:    "no name",
+			dc:      Datacenter{Name: "dc1", Port: "8200", Hosts: []Host{{Name: "vault-1"}, {}}},
+			wantErr: "datacenter dc1: host 2 has no name or address",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.dc.Resolve("https")
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("Resolve() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+
+			var got []string
+			for _, host := range test.dc.Hosts {
+				got = append(got, host.Protocol+"://"+host.Name+":"+host.Port)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Resolve() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
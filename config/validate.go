@@ -0,0 +1,331 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedFormat is returned by Validate for config files it can't
+// check line by line, like HCL and TOML
+var ErrUnsupportedFormat = errors.New("only YAML and JSON config files can be validated")
+
+// Problem is something wrong with a config file
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+var tlsFields = []string{"ca_cert", "ca_path", "client_cert", "client_key", "tls_server_name", "tls_skip_verify"}
+
+var (
+	topLevelFields   = append([]string{"gpg", "capath", "protocol", "datacenter", "datacenters"}, tlsFields...)
+	datacenterFields = append([]string{"name", "keys", "hosts", "protocol", "port", "domain", "tags", "labels"}, tlsFields...)
+	hostFields       = append([]string{"name", "port", "protocol", "address", "tags", "labels"}, tlsFields...)
+	keyFields        = []string{"key"}
+)
+
+// Validate checks the config file at path for mistakes, returning every
+// problem found with the line it is on. Only YAML and JSON files can be
+// validated.
+func Validate(path string) ([]Problem, error) {
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json", "":
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validator{file: path}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(contents, &document); err != nil {
+		v.add(nil, "%s", err)
+		return v.problems, nil
+	}
+
+	if len(document.Content) == 0 {
+		v.add(nil, "config file is empty")
+		return v.problems, nil
+	}
+
+	v.validateRoot(document.Content[0])
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].Line < v.problems[j].Line
+	})
+
+	return v.problems, nil
+}
+
+type validator struct {
+	file     string
+	problems []Problem
+}
+
+func (v *validator) add(node *yaml.Node, format string, args ...interface{}) {
+	problem := Problem{File: v.file, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		problem.Line = node.Line
+	}
+	v.problems = append(v.problems, problem)
+}
+
+// fields returns the values of a mapping by key, reporting unknown and
+// repeated keys
+func (v *validator) fields(node *yaml.Node, what string, known []string) map[string]*yaml.Node {
+
+	fields := make(map[string]*yaml.Node)
+	if node.Kind != yaml.MappingNode {
+		v.add(node, "%s must be a map", what)
+		return fields
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		if !contains(known, key.Value) {
+			v.add(key, "unknown field %q in %s", key.Value, what)
+			continue
+		}
+		if _, ok := fields[key.Value]; ok {
+			v.add(key, "field %q is set more than once in %s", key.Value, what)
+		}
+		fields[key.Value] = value
+	}
+
+	return fields
+}
+
+func (v *validator) validateRoot(root *yaml.Node) {
+
+	fields := v.fields(root, "the config file", topLevelFields)
+
+	gpg := false
+	if node, ok := fields["gpg"]; ok {
+		gpg = v.bool(node, "gpg")
+	}
+	if node, ok := fields["protocol"]; ok {
+		v.protocol(node)
+	}
+	if node, ok := fields["tls_skip_verify"]; ok {
+		v.bool(node, "tls_skip_verify")
+	}
+
+	datacenters, ok := fields["datacenters"]
+	if !ok {
+		v.add(root, "no datacenters are configured")
+		return
+	}
+	if datacenters.Kind != yaml.SequenceNode {
+		v.add(datacenters, "datacenters must be a list")
+		return
+	}
+	if len(datacenters.Content) == 0 {
+		v.add(datacenters, "no datacenters are configured")
+	}
+
+	dcNames := make(map[string]int)
+	hosts := make(map[string]int)
+	for _, dc := range datacenters.Content {
+		v.validateDatacenter(dc, gpg, dcNames, hosts)
+	}
+}
+
+func (v *validator) validateDatacenter(dc *yaml.Node, gpg bool, dcNames, hosts map[string]int) {
+
+	fields := v.fields(dc, "datacenter", datacenterFields)
+
+	name := ""
+	if node, ok := fields["name"]; ok && node.Value != "" {
+		name = node.Value
+		if line, ok := dcNames[name]; ok {
+			v.add(node, "duplicate datacenter %q, first defined on line %d", name, line)
+		} else {
+			dcNames[name] = node.Line
+		}
+	} else {
+		v.add(dc, "datacenter has no name")
+	}
+	what := fmt.Sprintf("datacenter %q", name)
+
+	if node, ok := fields["protocol"]; ok {
+		v.protocol(node)
+	}
+	if node, ok := fields["tls_skip_verify"]; ok {
+		v.bool(node, "tls_skip_verify")
+	}
+	v.tagsAndLabels(fields)
+	dcPort := ""
+	if node, ok := fields["port"]; ok {
+		dcPort = node.Value
+		v.port(node, dcPort)
+	}
+	domain := ""
+	if node, ok := fields["domain"]; ok {
+		domain = strings.TrimPrefix(node.Value, ".")
+	}
+
+	keys, ok := fields["keys"]
+	switch {
+	case !ok || (keys.Kind == yaml.SequenceNode && len(keys.Content) == 0):
+		v.add(dc, "%s has no keys", what)
+	case keys.Kind != yaml.SequenceNode:
+		v.add(keys, "keys in %s must be a list", what)
+	default:
+		for _, key := range keys.Content {
+			keyFields := v.fields(key, "key", keyFields)
+			value, ok := keyFields["key"]
+			if !ok || value.Value == "" {
+				v.add(key, "empty key in %s", what)
+				continue
+			}
+			if gpg {
+				if _, err := base64.StdEncoding.DecodeString(value.Value); err != nil {
+					v.add(value, "key in %s is not valid base64, which it must be when gpg is true", what)
+				}
+			}
+		}
+	}
+
+	hostList, ok := fields["hosts"]
+	switch {
+	case !ok || (hostList.Kind == yaml.SequenceNode && len(hostList.Content) == 0):
+		v.add(dc, "%s has no hosts", what)
+	case hostList.Kind != yaml.SequenceNode:
+		v.add(hostList, "hosts in %s must be a list", what)
+	default:
+		for _, host := range hostList.Content {
+			v.validateHost(host, what, dcPort, domain, hosts)
+		}
+	}
+}
+
+func (v *validator) validateHost(host *yaml.Node, dcWhat, dcPort, domain string, hosts map[string]int) {
+
+	fields := v.fields(host, "host", hostFields)
+
+	if node, ok := fields["protocol"]; ok {
+		v.protocol(node)
+	}
+	if node, ok := fields["tls_skip_verify"]; ok {
+		v.bool(node, "tls_skip_verify")
+	}
+	v.tagsAndLabels(fields)
+
+	name, port := "", ""
+	if node, ok := fields["port"]; ok {
+		port = node.Value
+		v.port(node, port)
+	}
+
+	if node, ok := fields["address"]; ok {
+		address, err := url.Parse(node.Value)
+		if err != nil || address.Scheme == "" || address.Hostname() == "" {
+			v.add(node, "address %q in %s must be a URL like https://vault-1:8200", node.Value, dcWhat)
+			return
+		}
+		name = address.Hostname()
+		if address.Port() != "" {
+			port = address.Port()
+		}
+	} else if node, ok := fields["name"]; ok && node.Value != "" {
+		name = node.Value
+		if domain != "" && !strings.Contains(name, ".") {
+			name = name + "." + domain
+		}
+	} else {
+		v.add(host, "host in %s has no name or address", dcWhat)
+		return
+	}
+
+	if port == "" {
+		port = dcPort
+	}
+	if port == "" {
+		v.add(host, "host %q in %s has no port", name, dcWhat)
+		return
+	}
+
+	endpoint := strings.ToLower(name) + ":" + port
+	if line, ok := hosts[endpoint]; ok {
+		v.add(host, "duplicate host %s, first defined on line %d", endpoint, line)
+		return
+	}
+	hosts[endpoint] = host.Line
+}
+
+func (v *validator) tagsAndLabels(fields map[string]*yaml.Node) {
+	if tags, ok := fields["tags"]; ok {
+		if tags.Kind != yaml.SequenceNode {
+			v.add(tags, "tags must be a list")
+		} else {
+			for _, tag := range tags.Content {
+				if tag.Kind != yaml.ScalarNode || tag.Value == "" {
+					v.add(tag, "tags must be strings")
+				}
+			}
+		}
+	}
+	if labels, ok := fields["labels"]; ok {
+		if labels.Kind != yaml.MappingNode {
+			v.add(labels, "labels must be a map")
+		} else {
+			for _, value := range labels.Content {
+				if value.Kind != yaml.ScalarNode {
+					v.add(value, "labels must be strings")
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) port(node *yaml.Node, port string) {
+	if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+		v.add(node, "port %q must be a number between 1 and 65535", port)
+	}
+}
+
+func (v *validator) protocol(node *yaml.Node) {
+	if node.Value != "http" && node.Value != "https" {
+		v.add(node, "protocol %q must be http or https", node.Value)
+	}
+}
+
+func (v *validator) bool(node *yaml.Node, field string) bool {
+	value, err := strconv.ParseBool(node.Value)
+	if err != nil || node.Kind != yaml.ScalarNode {
+		v.add(node, "%s must be true or false", field)
+	}
+	return value
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
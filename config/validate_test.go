@@ -0,0 +1,222 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "hookpick")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func writeConfig(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestValidate(t *testing.T) {
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		contents string
+		want     []Problem
+	}{
+		{
+			name: "valid",
+			contents: `gpg: false
+datacenters:
+- name: dc1
+  port: 8200
+  keys:
+  - key: abc
+  hosts:
+  - name: vault-1
+  - address: https://vault-2:8300
+`,
+		},
+		{
+			name:     "empty",
+			contents: ``,
+			want:     []Problem{{Message: "config file is empty"}},
+		},
+		{
+			name:     "no datacenters",
+			contents: "gpg: false\n",
+			want:     []Problem{{Line: 1, Message: "no datacenters are configured"}},
+		},
+		{
+			name: "unknown and repeated fields",
+			contents: `datacenters:
+- name: dc1
+  nmae: dc2
+  keys:
+  - key: abc
+  hosts:
+  - name: vault-1
+    port: 8200
+    port: 8201
+`,
+			want: []Problem{
+				{Line: 3, Message: `unknown field "nmae" in datacenter`},
+				{Line: 9, Message: `field "port" is set more than once in host`},
+			},
+		},
+		{
+			name: "duplicates",
+			contents: `datacenters:
+- name: dc1
+  domain: example.com
+  keys:
+  - key: abc
+  hosts:
+  - name: vault-1
+    port: 8200
+  - name: VAULT-1.example.com
+    port: 8200
+- name: dc1
+  keys:
+  - key: abc
+  hosts:
+  - address: https://vault-1.example.com:8200
+`,
+			want: []Problem{
+				{Line: 9, Message: "duplicate host vault-1.example.com:8200, first defined on line 7"},
+				{Line: 11, Message: `duplicate datacenter "dc1", first defined on line 2`},
+				{Line: 15, Message: "duplicate host vault-1.example.com:8200, first defined on line 7"},
+			},
+		},
+		{
+			name: "missing ports, keys and hosts",
+			contents: `datacenters:
+- name: dc1
+  keys: []
+  hosts:
+  - name: vault-1
+- name: dc2
+  keys:
+  - key: ""
+`,
+			want: []Problem{
+				{Line: 2, Message: `datacenter "dc1" has no keys`},
+				{Line: 5, Message: `host "vault-1" in datacenter "dc1" has no port`},
+				{Line: 6, Message: `datacenter "dc2" has no hosts`},
+				{Line: 8, Message: `empty key in datacenter "dc2"`},
+			},
+		},
+		{
+			name: "invalid values",
+			contents: `gpg: true
+protocol: ftp
+datacenters:
+- name: dc1
+  port: 70000
+  keys:
+  - key: not base64!
+  hosts:
+  - address: vault-1
+  - name: vault-2
+    tls_skip_verify: maybe
+`,
+			want: []Problem{
+				{Line: 2, Message: `protocol "ftp" must be http or https`},
+				{Line: 5, Message: `port "70000" must be a number between 1 and 65535`},
+				{Line: 7, Message: `key in datacenter "dc1" is not valid base64, which it must be when gpg is true`},
+				{Line: 9, Message: `address "vault-1" in datacenter "dc1" must be a URL like https://vault-1:8200`},
+				{Line: 11, Message: "tls_skip_verify must be true or false"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeConfig(t, dir, "hookpick.yaml", test.contents)
+
+			problems, err := Validate(path)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			for i := range test.want {
+				test.want[i].File = path
+			}
+			if !reflect.DeepEqual(problems, test.want) {
+				t.Errorf("Validate() =\n%v\nwant\n%v", problems, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeConfig(t, dir, "hookpick.json", `{
+  "datacenters": [
+    {
+      "name": "dc1",
+      "keys": [{"key": "abc"}],
+      "hosts": [{"name": "vault-1", "port": "0"}]
+    }
+  ]
+}`)
+
+	problems, err := Validate(path)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	want := []Problem{{File: path, Line: 6, Message: `port "0" must be a number between 1 and 65535`}}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("Validate() = %v, want %v", problems, want)
+	}
+}
+
+func TestValidateUnsupportedFormat(t *testing.T) {
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"hookpick.hcl", "hookpick.toml"} {
+		path := writeConfig(t, dir, name, "")
+		if _, err := Validate(path); err != ErrUnsupportedFormat {
+			t.Errorf("Validate(%q) error = %v, want %v", name, err, ErrUnsupportedFormat)
+		}
+	}
+}
+
+func TestProblemString(t *testing.T) {
+
+	tests := []struct {
+		problem Problem
+		want    string
+	}{
+		{Problem{File: "hookpick.yaml", Line: 3, Message: "oops"}, "hookpick.yaml:3: oops"},
+		{Problem{File: "hookpick.yaml", Message: "oops"}, "hookpick.yaml: oops"},
+	}
+
+	for _, test := range tests {
+		if got := test.problem.String(); got != test.want {
+			t.Errorf("String() = %q, want %q", got, test.want)
+		}
+	}
+}
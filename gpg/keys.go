@@ -0,0 +1,58 @@
+package gpg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// ReadPublicKeyFile reads a PGP public key from disk and returns it in the
+// base64 encoded binary form Vault expects for pgp_keys. Armored, binary and
+// already base64 encoded keys are accepted, as are keybase:<user> references.
+func ReadPublicKeyFile(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	trimmed := strings.TrimSpace(string(contents))
+
+	// vault resolves keybase users itself
+	if strings.HasPrefix(trimmed, "keybase:") {
+		return trimmed, nil
+	}
+
+	if strings.HasPrefix(trimmed, "-----BEGIN PGP") {
+		block, err := armor.Decode(bytes.NewBufferString(trimmed))
+		if err != nil {
+			return "", err
+		}
+		raw, err := ioutil.ReadAll(block.Body)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(raw), nil
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(trimmed); err == nil {
+		return trimmed, nil
+	}
+
+	return base64.StdEncoding.EncodeToString(contents), nil
+}
+
+// ReadPublicKeyFiles reads each of the given PGP public key files, in order
+func ReadPublicKeyFiles(paths []string) ([]string, error) {
+	var keys []string
+	for _, path := range paths {
+		key, err := ReadPublicKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
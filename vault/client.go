@@ -1,18 +1,24 @@
 package vault
 
 import (
+	"net/http"
 	"net/url"
 	"strings"
 
 	vaultapi "github.com/hashicorp/vault/api"
+
+	"github.com/jaxxstorm/hookpick/config"
 )
 
 type VaultHelper struct {
-	HostName  string
-	Port      string
-	CAPath    string
-	Protocol  string
-	GetStatus VaultStatusGetter
+	HostName   string
+	Port       string
+	CAPath     string
+	Protocol   string
+	Datacenter string
+	Token      string
+	TLS        config.TLS
+	GetStatus  VaultStatusGetter
 }
 
 type VaultHelperGetter func(host, certpath, protocol, port string, sg VaultStatusGetter) *VaultHelper
@@ -36,23 +42,44 @@ func (helper *VaultHelper) GetVaultClient() (*vaultapi.Client, error) {
 	}
 
 	// create a vault config
-	config := &vaultapi.Config{Address: hostURL.String()}
+	clientConfig := &vaultapi.Config{Address: hostURL.String()}
 
 	// read in any environment variables that might be set
-	if err := config.ReadEnvironment(); err != nil {
+	if err := clientConfig.ReadEnvironment(); err != nil {
 		return nil, err
 	}
 
-	// Set the CA path, if it's present
-	if err := config.ConfigureTLS(&vaultapi.TLSConfig{CAPath: helper.CAPath}); err != nil {
+	// the host's TLS settings override the environment, falling back to the CA path
+	tlsConfig := helper.TLS
+	if tlsConfig.CACert == "" && tlsConfig.CAPath == "" {
+		tlsConfig.CAPath = helper.CAPath
+	}
+
+	if err := clientConfig.ConfigureTLS(&vaultapi.TLSConfig{
+		CACert:        tlsConfig.CACert,
+		CAPath:        tlsConfig.CAPath,
+		ClientCert:    tlsConfig.ClientCert,
+		ClientKey:     tlsConfig.ClientKey,
+		TLSServerName: tlsConfig.TLSServerName,
+	}); err != nil {
 		return nil, err
 	}
 
+	// ConfigureTLS can only turn verification off, so set it ourselves
+	if tlsConfig.TLSSkipVerify != nil {
+		clientConfig.HttpClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = *tlsConfig.TLSSkipVerify
+	}
+
 	// create the client
-	client, err := vaultapi.NewClient(config)
+	client, err := vaultapi.NewClient(clientConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	// only override the token read from the environment if we were given one
+	if helper.Token != "" {
+		client.SetToken(helper.Token)
+	}
+
 	return client, nil
 }
@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const otpCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// GenerateOTP creates a random base62 one time password of the given length
+// to encode a generated root token with. Older versions of vault report a
+// length of 0 and expect a base64 encoded 16 byte OTP instead.
+func GenerateOTP(length int) (string, error) {
+	if length < 0 {
+		return "", errors.New("invalid OTP length")
+	}
+
+	if length == 0 {
+		otp := make([]byte, 16)
+		if _, err := rand.Read(otp); err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(otp), nil
+	}
+
+	max := big.NewInt(int64(len(otpCharset)))
+	otp := make([]byte, length)
+	for i := range otp {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		otp[i] = otpCharset[n.Int64()]
+	}
+
+	return string(otp), nil
+}
+
+// DecodeRootToken decodes an encoded root token using the OTP it was generated with
+func DecodeRootToken(encoded, otp string) (string, error) {
+
+	// older versions of vault use a base64 encoded 16 byte OTP
+	// and produce UUID style tokens
+	if otpBytes, err := base64.StdEncoding.DecodeString(otp); err == nil && len(otpBytes) == 16 {
+		tokenBytes, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", err
+		}
+
+		uuid, err := xorBytes(tokenBytes, otpBytes)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+	}
+
+	tokenBytes, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		tokenBytes, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	token, err := xorBytes(tokenBytes, []byte(otp))
+	if err != nil {
+		return "", err
+	}
+
+	return string(token), nil
+}
+
+func xorBytes(a, b []byte) ([]byte, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("length of encoded token (%d) does not match OTP (%d)", len(a), len(b))
+	}
+
+	result := make([]byte, len(a))
+	for i := range a {
+		result[i] = a[i] ^ b[i]
+	}
+
+	return result, nil
+}
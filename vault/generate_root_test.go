@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestGenerateOTP(t *testing.T) {
+
+	tests := []struct {
+		length  int
+		wantLen int
+		wantErr bool
+	}{
+		{length: 28, wantLen: 28},
+		{length: 26, wantLen: 26},
+		{length: 0, wantLen: 24},
+		{length: -1, wantErr: true},
+	}
+
+	for _, test := range tests {
+		otp, err := GenerateOTP(test.length)
+		if (err != nil) != test.wantErr {
+			t.Errorf("GenerateOTP(%d) error = %v, wantErr %v", test.length, err, test.wantErr)
+			continue
+		}
+		if len(otp) != test.wantLen {
+			t.Errorf("GenerateOTP(%d) = %q, want length %d", test.length, otp, test.wantLen)
+		}
+	}
+
+	// the legacy OTP is 16 random bytes, base64 encoded
+	otp, _ := GenerateOTP(0)
+	if decoded, err := base64.StdEncoding.DecodeString(otp); err != nil || len(decoded) != 16 {
+		t.Errorf("GenerateOTP(0) = %q, want a base64 encoded 16 byte OTP", otp)
+	}
+
+	otp, _ = GenerateOTP(28)
+	if strings.Trim(otp, otpCharset) != "" {
+		t.Errorf("GenerateOTP(28) = %q, want only base62 characters", otp)
+	}
+}
+
+func TestDecodeRootToken(t *testing.T) {
+
+	xor := func(a, b []byte) []byte {
+		out, err := xorBytes(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	// newer vault XORs the token with the OTP and encodes it without padding
+	otp := "hVBBvcBQb4ZVpqLDSlgEOIwB6yEH"
+	token := "s.NOTAREALTOKENx1234567890ab"
+	encoded := base64.RawStdEncoding.EncodeToString(xor([]byte(token), []byte(otp)))
+
+	// older vault XORs the 16 bytes of a UUID with a base64 encoded OTP
+	legacyOTPBytes := []byte("0123456789abcdef")
+	legacyOTP := base64.StdEncoding.EncodeToString(legacyOTPBytes)
+	uuid := []byte{0x4f, 0x3a, 0x1c, 0x2d, 0x9e, 0x8b, 0x47, 0x6a, 0xb5, 0x01, 0xc2, 0xd3, 0xe4, 0xf5, 0x06, 0x17}
+	legacyEncoded := base64.StdEncoding.EncodeToString(xor(uuid, legacyOTPBytes))
+
+	tests := []struct {
+		name    string
+		encoded string
+		otp     string
+		want    string
+		wantErr bool
+	}{
+		{name: "otp", encoded: encoded, otp: otp, want: token},
+		{name: "otp with padding", encoded: base64.StdEncoding.EncodeToString(xor([]byte(token), []byte(otp))), otp: otp, want: token},
+		{name: "legacy otp", encoded: legacyEncoded, otp: legacyOTP, want: "4f3a1c2d-9e8b-476a-b501-c2d3e4f50617"},
+		{name: "wrong otp length", encoded: encoded, otp: otp[:20], wantErr: true},
+		{name: "not base64", encoded: "not base64!", otp: otp, wantErr: true},
+		{name: "legacy not base64", encoded: "not base64!", otp: legacyOTP, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DecodeRootToken(test.encoded, test.otp)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("DecodeRootToken() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("DecodeRootToken() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package vault
+
+import (
+	"context"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// Node roles reported by NodeDetails
+const (
+	RoleActive      = string(StateActive)
+	RoleStandby     = string(StateStandby)
+	RolePerfStandby = string(StatePerfStandby)
+	RoleDRSecondary = string(StateDRSecondary)
+	RoleSealed      = string(StateSealed)
+)
+
+// NodeInfo describes a single Vault node and its place in the cluster
+type NodeInfo struct {
+	Version            string
+	ClusterName        string
+	ClusterID          string
+	SealType           string
+	HAEnabled          bool
+	Role               string
+	LeaderAddress      string
+	RaftCommittedIndex uint64
+}
+
+// leaderResponse adds the raft fields newer versions of vault return to sys/leader
+type leaderResponse struct {
+	vaultapi.LeaderResponse
+	RaftCommittedIndex uint64 `json:"raft_committed_index"`
+}
+
+// NodeDetails gathers node details from seal-status, sys/health and sys/leader
+func NodeDetails(client *vaultapi.Client, sealStatus *vaultapi.SealStatusResponse) (*NodeInfo, error) {
+
+	info := &NodeInfo{
+		Version:     sealStatus.Version,
+		ClusterName: sealStatus.ClusterName,
+		ClusterID:   sealStatus.ClusterID,
+		SealType:    sealStatus.Type,
+	}
+
+	// a sealed node can't tell us anything about the cluster
+	if sealStatus.Sealed {
+		info.Role = RoleSealed
+		return info, nil
+	}
+
+	health, err := client.Sys().Health()
+	if err != nil {
+		return info, err
+	}
+
+	if health.Version != "" {
+		info.Version = health.Version
+	}
+	if health.ClusterName != "" {
+		info.ClusterName = health.ClusterName
+	}
+	if health.ClusterID != "" {
+		info.ClusterID = health.ClusterID
+	}
+
+	info.Role = string(healthState(health))
+
+	leader, err := getLeader(client)
+	if err != nil {
+		return info, err
+	}
+
+	info.HAEnabled = leader.HAEnabled
+	info.LeaderAddress = leader.LeaderAddress
+	info.RaftCommittedIndex = leader.RaftCommittedIndex
+
+	return info, nil
+}
+
+func getLeader(client *vaultapi.Client) (*leaderResponse, error) {
+	r := client.NewRequest("GET", "/v1/sys/leader")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := client.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result leaderResponse
+	err = resp.DecodeJSON(&result)
+	return &result, err
+}
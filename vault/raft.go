@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// RaftServer is a peer in the raft configuration
+type RaftServer struct {
+	NodeID          string `json:"node_id"`
+	Address         string `json:"address"`
+	Leader          bool   `json:"leader"`
+	ProtocolVersion string `json:"protocol_version"`
+	Voter           bool   `json:"voter"`
+}
+
+// RaftConfiguration is the response from sys/storage/raft/configuration
+type RaftConfiguration struct {
+	Servers []RaftServer `json:"servers"`
+	Index   uint64       `json:"index"`
+}
+
+// AutopilotServer is autopilot's view of a single raft peer
+type AutopilotServer struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	NodeStatus  string `json:"node_status"`
+	LastContact string `json:"last_contact"`
+	LastTerm    uint64 `json:"last_term"`
+	LastIndex   uint64 `json:"last_index"`
+	Healthy     bool   `json:"healthy"`
+	Status      string `json:"status"`
+}
+
+// AutopilotState is the response from sys/storage/raft/autopilot/state
+type AutopilotState struct {
+	Healthy          bool                        `json:"healthy"`
+	FailureTolerance int                         `json:"failure_tolerance"`
+	Leader           string                      `json:"leader"`
+	Voters           []string                    `json:"voters"`
+	Servers          map[string]*AutopilotServer `json:"servers"`
+}
+
+// GetRaftConfiguration reads the raft peer set. This needs a token.
+func GetRaftConfiguration(client *vaultapi.Client) (*RaftConfiguration, error) {
+
+	var result struct {
+		Data struct {
+			Config RaftConfiguration `json:"config"`
+		} `json:"data"`
+	}
+
+	found, err := getRaw(client, "/v1/sys/storage/raft/configuration", &result)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &result.Data.Config, nil
+}
+
+// GetAutopilotState reads the autopilot state. Versions of vault before
+// autopilot was added return nil with no error.
+func GetAutopilotState(client *vaultapi.Client) (*AutopilotState, error) {
+
+	var result struct {
+		Data AutopilotState `json:"data"`
+	}
+
+	found, err := getRaw(client, "/v1/sys/storage/raft/autopilot/state", &result)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &result.Data, nil
+}
+
+// getRaw decodes the JSON response from path into out. It returns false
+// if the path does not exist.
+func getRaw(client *vaultapi.Client, path string, out interface{}) (bool, error) {
+	r := client.NewRequest("GET", path)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := client.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, resp.DecodeJSON(out)
+}
@@ -5,24 +5,64 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-type VaultStatusGetter func(client *vaultapi.Client) (bool, bool)
+// HealthState is the state of a vault as reported by sys/health
+type HealthState string
 
-// Status - Get vault status
-func Status(client *vaultapi.Client) (bool, bool) {
+// States reported by Status
+const (
+	StateUnreachable   HealthState = "unreachable"
+	StateUninitialized HealthState = "uninitialized"
+	StateSealed        HealthState = "sealed"
+	StateStandby       HealthState = "standby"
+	StatePerfStandby   HealthState = "perf-standby"
+	StateDRSecondary   HealthState = "dr-secondary"
+	StateActive        HealthState = "active"
+)
 
-	// statuses
-	initStatus, err := client.Sys().InitStatus()
+// Reachable is true if the vault answered the health check
+func (s HealthState) Reachable() bool {
+	return s != StateUnreachable
+}
 
-	if err != nil {
-		log.WithFields(log.Fields{"host": client.Address()}).Error(err)
-	}
+// Initialized is true if the vault answered and has been initialized
+func (s HealthState) Initialized() bool {
+	return s != StateUnreachable && s != StateUninitialized
+}
+
+// Sealed is true if the vault answered and is sealed. Unreachable vaults are
+// not considered sealed, as we don't know.
+func (s HealthState) Sealed() bool {
+	return s == StateSealed
+}
+
+type VaultStatusGetter func(client *vaultapi.Client) (HealthState, error)
+
+// Status - Get vault status from sys/health
+func Status(client *vaultapi.Client) (HealthState, error) {
 
-	sealedStatus, err := client.Sys().SealStatus()
+	health, err := client.Sys().Health()
 
 	if err != nil {
-		log.WithFields(log.Fields{"host": client.Address()}).Error(err)
-		return true, initStatus
+		log.WithFields(log.Fields{"host": client.Address()}).Debugln(err)
+		return StateUnreachable, err
 	}
 
-	return sealedStatus.Sealed, initStatus
+	return healthState(health), nil
+}
+
+func healthState(health *vaultapi.HealthResponse) HealthState {
+	switch {
+	case !health.Initialized:
+		return StateUninitialized
+	case health.Sealed:
+		return StateSealed
+	case health.ReplicationDRMode == "secondary":
+		return StateDRSecondary
+	case health.PerformanceStandby:
+		return StatePerfStandby
+	case health.Standby:
+		return StateStandby
+	default:
+		return StateActive
+	}
 }
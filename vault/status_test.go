@@ -0,0 +1,90 @@
+package vault
+
+import (
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+func TestHealthState(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		health vaultapi.HealthResponse
+		want   HealthState
+	}{
+		{
+			name:   "uninitialized",
+			health: vaultapi.HealthResponse{Initialized: false, Sealed: true},
+			want:   StateUninitialized,
+		},
+		{
+			name:   "sealed",
+			health: vaultapi.HealthResponse{Initialized: true, Sealed: true, Standby: true},
+			want:   StateSealed,
+		},
+		{
+			name:   "dr secondary",
+			health: vaultapi.HealthResponse{Initialized: true, ReplicationDRMode: "secondary", Standby: true},
+			want:   StateDRSecondary,
+		},
+		{
+			name:   "dr primary",
+			health: vaultapi.HealthResponse{Initialized: true, ReplicationDRMode: "primary"},
+			want:   StateActive,
+		},
+		{
+			name:   "performance standby",
+			health: vaultapi.HealthResponse{Initialized: true, Standby: true, PerformanceStandby: true},
+			want:   StatePerfStandby,
+		},
+		{
+			name:   "standby",
+			health: vaultapi.HealthResponse{Initialized: true, Standby: true},
+			want:   StateStandby,
+		},
+		{
+			name:   "active",
+			health: vaultapi.HealthResponse{Initialized: true},
+			want:   StateActive,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := healthState(&test.health); got != test.want {
+				t.Errorf("healthState() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHealthStatePredicates(t *testing.T) {
+
+	tests := []struct {
+		state       HealthState
+		reachable   bool
+		initialized bool
+		sealed      bool
+	}{
+		{StateUnreachable, false, false, false},
+		{StateUninitialized, true, false, false},
+		{StateSealed, true, true, true},
+		{StateStandby, true, true, false},
+		{StatePerfStandby, true, true, false},
+		{StateDRSecondary, true, true, false},
+		{StateActive, true, true, false},
+	}
+
+	for _, test := range tests {
+		if got := test.state.Reachable(); got != test.reachable {
+			t.Errorf("%s.Reachable() = %v, want %v", test.state, got, test.reachable)
+		}
+		if got := test.state.Initialized(); got != test.initialized {
+			t.Errorf("%s.Initialized() = %v, want %v", test.state, got, test.initialized)
+		}
+		if got := test.state.Sealed(); got != test.sealed {
+			t.Errorf("%s.Sealed() = %v, want %v", test.state, got, test.sealed)
+		}
+	}
+}